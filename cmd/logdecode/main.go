@@ -0,0 +1,103 @@
+// package main is a filter restoring the fields compressed by the logger in JSON log lines read from stdin
+//
+// Usage:
+//
+//	kubectl logs pod | logdecode
+//	kubectl logs pod | logdecode -reassemble
+//
+// Lines that are not JSON objects are passed through. With -reassemble, chunked entries are
+// also stitched back together, and lines that are not JSON objects are dropped.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nullify-platform/logger/pkg/logger/chunking"
+)
+
+func main() {
+	reassemble := flag.Bool("reassemble", false, "stitch chunked log entries back together")
+	flag.Parse()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	var err error
+	if *reassemble {
+		err = reassembleLines(os.Stdin, out)
+	} else {
+		err = decodeLines(os.Stdin, out)
+	}
+	if err != nil {
+		out.Flush()
+		fmt.Fprintln(os.Stderr, "logdecode:", err)
+		os.Exit(1)
+	}
+}
+
+// decodeLines writes each line of r to w with its compressed fields decoded
+func decodeLines(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if writeErr := writeLine(w, line); writeErr != nil {
+				return writeErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeLine writes line to w, re-encoded with its compressed fields decoded if it has any
+func writeLine(w io.Writer, line []byte) error {
+	var fields map[string]any
+	if json.Unmarshal(line, &fields) != nil || fields == nil {
+		_, err := w.Write(line)
+		return err
+	}
+
+	// fields that fail to decode are kept as they are
+	size := len(fields)
+	_ = chunking.Decode(fields)
+	if len(fields) == size {
+		// no field was decoded, keep the line as written
+		_, err := w.Write(line)
+		return err
+	}
+
+	return writeFields(w, fields)
+}
+
+// reassembleLines writes the reassembled entries read from r to w
+func reassembleLines(r io.Reader, w io.Writer) error {
+	for entry := range chunking.Reassemble(r) {
+		if err := writeFields(w, entry.Fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFields writes fields to w as a JSON line
+func writeFields(w io.Writer, fields map[string]any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(fields); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
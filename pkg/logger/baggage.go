@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultBaggageMaxBytes is the default cap on the encoded baggage header written by LogConfigPropagator
+	DefaultBaggageMaxBytes = 1024
+
+	// baggageFieldsEnvVar is a comma-separated allowlist of LogConfig json keys propagated in baggage
+	baggageFieldsEnvVar = "LOG_CONFIG_BAGGAGE_FIELDS"
+	// baggageExtractFieldsEnvVar is a comma-separated allowlist of LogConfig json keys restored from baggage
+	baggageExtractFieldsEnvVar = "LOG_CONFIG_BAGGAGE_EXTRACT_FIELDS"
+	// baggageMaxBytesEnvVar overrides DefaultBaggageMaxBytes
+	baggageMaxBytesEnvVar = "LOG_CONFIG_BAGGAGE_MAX_BYTES"
+
+	baggageHeader = "baggage"
+
+	// baggageKeyPrefix namespaces LogConfig members among other baggage members
+	baggageKeyPrefix = "nullify."
+)
+
+// LogConfigPropagator is a W3C baggage propagator that also carries the allowlisted
+// LogConfig metadata of the context across service boundaries. On inject the metadata
+// is added to the baggage in the context, on extract the metadata of the separate extract
+// allowlist is restored with WithLogConfig. It replaces propagation.Baggage, so both should
+// not be registered together.
+//
+// Extracted metadata is trusted like metadata set by the service, e.g. to match tenant overrides,
+// so the extract allowlist is empty by default. Only set it, with WithExtractFields, in services
+// whose extracted carriers all come from trusted callers, and never at public ingress.
+type LogConfigPropagator struct {
+	fields        []metadataField
+	extractFields []metadataField
+	maxBytes      int
+}
+
+// LogConfigPropagatorOption configures a LogConfigPropagator created with NewLogConfigPropagator
+type LogConfigPropagatorOption func(*LogConfigPropagator)
+
+// WithExtractFields sets the LogConfig json keys restored from the baggage of extracted carriers.
+// Unknown keys are ignored. The default is none, see LogConfigPropagator.
+func WithExtractFields(allowlist []string) LogConfigPropagatorOption {
+	return func(p *LogConfigPropagator) {
+		p.extractFields = logConfigFields(allowlist)
+	}
+}
+
+var _ propagation.TextMapPropagator = &LogConfigPropagator{}
+
+// NewLogConfigPropagator creates a LogConfigPropagator injecting the given LogConfig json keys,
+// e.g. repositoryName or installationId. Unknown keys are ignored. When the encoded baggage
+// exceeds maxBytes, metadata members are dropped from the end of the allowlist first.
+// No metadata is extracted unless WithExtractFields is given.
+func NewLogConfigPropagator(allowlist []string, maxBytes int, opts ...LogConfigPropagatorOption) *LogConfigPropagator {
+	if maxBytes <= 0 {
+		maxBytes = DefaultBaggageMaxBytes
+	}
+
+	p := &LogConfigPropagator{fields: logConfigFields(allowlist), maxBytes: maxBytes}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// logConfigFields returns the LogConfig fields of the json keys of allowlist, ignoring unknown keys
+func logConfigFields(allowlist []string) []metadataField {
+	var fields []metadataField
+	for _, key := range allowlist {
+		if f, ok := logConfigPlan.field(strings.TrimSpace(key)); ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Inject sets the baggage of ctx, including the allowlisted LogConfig metadata, into carrier
+func (p *LogConfigPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	bag := baggage.FromContext(ctx)
+	cfg := reflect.ValueOf(LogConfigFromContext(ctx))
+
+	var added []string
+	for _, f := range p.fields {
+		value := cfg.FieldByIndex(f.index).String()
+		if value == "" {
+			continue
+		}
+
+		member, err := baggage.NewMemberRaw(baggageKeyPrefix+f.key, value)
+		if err != nil {
+			continue
+		}
+
+		if bag, err = bag.SetMember(member); err == nil {
+			added = append(added, member.Key())
+		}
+	}
+
+	encoded := bag.String()
+	for len(encoded) > p.maxBytes && len(added) > 0 {
+		bag = bag.DeleteMember(added[len(added)-1])
+		added = added[:len(added)-1]
+		encoded = bag.String()
+	}
+
+	if encoded != "" {
+		carrier.Set(baggageHeader, encoded)
+	}
+}
+
+// Extract restores the baggage, and the LogConfig metadata of the extract allowlist, from carrier into ctx
+func (p *LogConfigPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	bag, err := baggage.Parse(carrier.Get(baggageHeader))
+	if err != nil || bag.Len() == 0 {
+		return ctx
+	}
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	var cfg LogConfig
+	v := reflect.ValueOf(&cfg).Elem()
+	found := false
+	for _, f := range p.extractFields {
+		value := bag.Member(baggageKeyPrefix + f.key).Value()
+		if value == "" || len(value) > p.maxBytes {
+			continue
+		}
+		v.FieldByIndex(f.index).SetString(value)
+		found = true
+	}
+
+	if found {
+		ctx = WithLogConfig(ctx, cfg)
+	}
+	return ctx
+}
+
+// Fields returns the keys set by Inject
+func (p *LogConfigPropagator) Fields() []string {
+	return []string{baggageHeader}
+}
+
+// logConfigPropagatorFromEnv returns a LogConfigPropagator configured from LOG_CONFIG_BAGGAGE_FIELDS,
+// LOG_CONFIG_BAGGAGE_EXTRACT_FIELDS and LOG_CONFIG_BAGGAGE_MAX_BYTES, or nil if no fields are allowlisted
+func logConfigPropagatorFromEnv() *LogConfigPropagator {
+	fields := os.Getenv(baggageFieldsEnvVar)
+	extractFields := os.Getenv(baggageExtractFieldsEnvVar)
+	if fields == "" && extractFields == "" {
+		return nil
+	}
+
+	maxBytes := DefaultBaggageMaxBytes
+	if raw := os.Getenv(baggageMaxBytesEnvVar); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			zap.L().Error("failed to parse baggage max bytes, using default", zap.Error(err))
+		} else {
+			maxBytes = parsed
+		}
+	}
+
+	return NewLogConfigPropagator(strings.Split(fields, ","), maxBytes, WithExtractFields(strings.Split(extractFields, ",")))
+}
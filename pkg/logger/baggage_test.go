@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func setTestPropagator(t *testing.T, p propagation.TextMapPropagator) {
+	original := otel.GetTextMapPropagator()
+	t.Cleanup(func() { otel.SetTextMapPropagator(original) })
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, p))
+}
+
+func TestLogConfigPropagatorRoundTrip(t *testing.T) {
+	allowlist := []string{"repositoryName", "installationId", "organizationId", "unknownKey"}
+	setTestPropagator(t, NewLogConfigPropagator(allowlist, 0, WithExtractFields(allowlist)))
+
+	ctx := WithRepositoryMetadata(t.Context(), Repository{
+		Name:           "logger repo",
+		InstallationID: "42",
+		OrganizationID: "org-1",
+		CloneURL:       "https://example.com/secret.git",
+	})
+
+	attributes := map[string]string{}
+	tracer.InjectTracingIntoCustomMessage(ctx, attributes)
+	require.Contains(t, attributes, "baggage")
+	assert.NotContains(t, attributes["baggage"], "cloneUrl")
+
+	restored := tracer.ExtractTracingFromCustomEventMessage(t.Context(), attributes)
+	assert.Equal(t, Repository{Name: "logger repo", InstallationID: "42", OrganizationID: "org-1"}, LogConfigFromContext(restored).Repository)
+	assert.Equal(t, "42", baggage.FromContext(restored).Member("nullify.installationId").Value())
+}
+
+func TestLogConfigPropagatorKeepsExistingBaggage(t *testing.T) {
+	setTestPropagator(t, NewLogConfigPropagator([]string{"toolName"}, 0, WithExtractFields([]string{"toolName"})))
+
+	member, err := baggage.NewMember("tenant", "acme")
+	require.NoError(t, err)
+	bag, err := baggage.New(member)
+	require.NoError(t, err)
+
+	ctx := baggage.ContextWithBaggage(t.Context(), bag)
+	ctx = WithToolMetadata(ctx, Tool{Name: "semgrep"})
+
+	headers := http.Header{}
+	tracer.InjectTracingIntoHTTPHeaders(ctx, headers)
+
+	restored := tracer.ExtractTracingFromHTTPHeaders(t.Context(), headers)
+	assert.Equal(t, "acme", baggage.FromContext(restored).Member("tenant").Value())
+	assert.Equal(t, "semgrep", LogConfigFromContext(restored).Tool.Name)
+}
+
+func TestLogConfigPropagatorExtractsNothingByDefault(t *testing.T) {
+	setTestPropagator(t, NewLogConfigPropagator([]string{"organizationId", "installationId"}, 0))
+
+	// an external caller cannot set the metadata matched by tenant overrides
+	headers := http.Header{"Baggage": []string{"nullify.organizationId=org-1,nullify.installationId=42,tenant=acme"}}
+	restored := tracer.ExtractTracingFromHTTPHeaders(t.Context(), headers)
+	assert.Equal(t, LogConfig{}, LogConfigFromContext(restored))
+	assert.Equal(t, "acme", baggage.FromContext(restored).Member("tenant").Value())
+
+	// only the keys of the extract allowlist are restored
+	setTestPropagator(t, NewLogConfigPropagator(nil, 0, WithExtractFields([]string{"installationId"})))
+	restored = tracer.ExtractTracingFromHTTPHeaders(t.Context(), headers)
+	assert.Equal(t, Repository{InstallationID: "42"}, LogConfigFromContext(restored).Repository)
+}
+
+func TestLogConfigPropagatorSizeCap(t *testing.T) {
+	p := NewLogConfigPropagator([]string{"repositoryName", "cloneUrl"}, 64)
+
+	ctx := WithRepositoryMetadata(t.Context(), Repository{
+		Name:     "logger",
+		CloneURL: "https://example.com/" + strings.Repeat("a", 100) + ".git",
+	})
+
+	carrier := propagation.MapCarrier{}
+	p.Inject(ctx, carrier)
+
+	assert.Equal(t, "nullify.repositoryName=logger", carrier.Get("baggage"))
+}
+
+func TestLogConfigPropagatorDisabled(t *testing.T) {
+	p := NewLogConfigPropagator(nil, 0)
+
+	carrier := propagation.MapCarrier{}
+	p.Inject(WithRepositoryMetadata(t.Context(), Repository{Name: "logger"}), carrier)
+	assert.Empty(t, carrier)
+
+	restored := p.Extract(t.Context(), propagation.MapCarrier{"baggage": "nullify.repositoryName=logger"})
+	assert.Equal(t, LogConfig{}, LogConfigFromContext(restored))
+}
+
+func TestLogConfigPropagatorFromEnv(t *testing.T) {
+	assert.Nil(t, logConfigPropagatorFromEnv())
+
+	t.Setenv("LOG_CONFIG_BAGGAGE_FIELDS", "repositoryName, installationId")
+	t.Setenv("LOG_CONFIG_BAGGAGE_MAX_BYTES", "256")
+
+	p := logConfigPropagatorFromEnv()
+	require.NotNil(t, p)
+	assert.Len(t, p.fields, 2)
+	assert.Empty(t, p.extractFields)
+	assert.Equal(t, 256, p.maxBytes)
+
+	t.Setenv("LOG_CONFIG_BAGGAGE_FIELDS", "")
+	t.Setenv("LOG_CONFIG_BAGGAGE_EXTRACT_FIELDS", "installationId")
+
+	p = logConfigPropagatorFromEnv()
+	require.NotNil(t, p)
+	assert.Empty(t, p.fields)
+	assert.Len(t, p.extractFields, 1)
+}
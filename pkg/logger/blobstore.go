@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// BlobStore stores the values offloaded from oversized log entries, see OffloadOversized
+type BlobStore interface {
+	// Put stores data under key and returns the URI of the object
+	Put(ctx context.Context, key string, data []byte) (string, error)
+}
+
+// FileBlobStore is a BlobStore writing objects to a local directory, for development and tests
+type FileBlobStore struct {
+	dir string
+}
+
+// NewFileBlobStore returns a FileBlobStore writing to dir, creating it if needed
+func NewFileBlobStore(dir string) (*FileBlobStore, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileBlobStore{dir: dir}, nil
+}
+
+// Put writes data to a file named key in the directory and returns its file:// URI
+func (s *FileBlobStore) Put(_ context.Context, key string, data []byte) (string, error) {
+	name := filepath.Join(s.dir, filepath.Base(key))
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		return "", err
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(name)}).String(), nil
+}
+
+// S3PutObjectAPI is the part of the S3 client used by S3BlobStore
+type S3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// S3BlobStore is a BlobStore writing objects to an S3 bucket
+type S3BlobStore struct {
+	client S3PutObjectAPI
+	bucket string
+	prefix string
+}
+
+// NewS3BlobStore returns an S3BlobStore writing objects to bucket, with keys under prefix
+func NewS3BlobStore(client S3PutObjectAPI, bucket, prefix string) *S3BlobStore {
+	return &S3BlobStore{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Put uploads data to the bucket and returns its s3:// URI
+func (s *S3BlobStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	key = path.Join(s.prefix, key)
+
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	if _, err := s.client.PutObject(ctx, input); err != nil {
+		return "", fmt.Errorf("failed to upload log payload to s3://%s/%s: %w", s.bucket, key, err)
+	}
+
+	return "s3://" + s.bucket + "/" + key, nil
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3Client records the objects put to it
+type fakeS3Client struct {
+	input *s3.PutObjectInput
+	body  []byte
+	err   error
+}
+
+func (c *fakeS3Client) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.input = params
+	c.body, _ = io.ReadAll(params.Body)
+	return &s3.PutObjectOutput{}, c.err
+}
+
+func TestFileBlobStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := NewFileBlobStore(dir)
+	require.NoError(t, err)
+
+	uri, err := store.Put(t.Context(), "abc.txt", []byte("payload"))
+	require.NoError(t, err)
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "abc.txt")), uri)
+
+	data, err := os.ReadFile(filepath.Join(dir, "abc.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(data))
+}
+
+func TestS3BlobStore(t *testing.T) {
+	client := &fakeS3Client{}
+	store := NewS3BlobStore(client, "logs-bucket", "payloads/prod")
+
+	uri, err := store.Put(t.Context(), "abc.json", []byte(`{"a":1}`))
+	require.NoError(t, err)
+	assert.Equal(t, "s3://logs-bucket/payloads/prod/abc.json", uri)
+
+	assert.Equal(t, "logs-bucket", aws.ToString(client.input.Bucket))
+	assert.Equal(t, "payloads/prod/abc.json", aws.ToString(client.input.Key))
+	assert.Equal(t, "application/json", aws.ToString(client.input.ContentType))
+	assert.Equal(t, `{"a":1}`, string(client.body))
+
+	client.err = errors.New("access denied")
+	_, err = store.Put(t.Context(), "abc.json", []byte(`{"a":1}`))
+	assert.ErrorContains(t, err, "access denied")
+}
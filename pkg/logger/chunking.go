@@ -1,82 +1,441 @@
 package logger
 
 import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 const (
-	// MaxStringFieldSize is the maximum size in bytes for a single string field
+	// MaxStringFieldSize was the maximum size in bytes of a single string field before it was split.
+	//
+	// Deprecated: entries are now split by their encoded size, see DefaultMaxEntrySize and WithMaxEntrySize.
+	MaxStringFieldSize = 204800 // 200KB per chunk
+
+	// DefaultMaxEntrySize is the default maximum size in bytes of an encoded log entry
 	// before it gets split across multiple log entries. This prevents oversized
 	// log entries from being rejected by log aggregation backends
 	// (e.g. Grafana Loki's 256KB per-entry limit).
-	MaxStringFieldSize = 204800 // 200KB per chunk
+	DefaultMaxEntrySize = 256000
+
+	// MaxChunksPerEntry caps the number of log entries a single log call is split into.
+	// Oversized values are truncated after this many chunks.
+	MaxChunksPerEntry = 16
+
+	// truncationMarker is appended to a value cut short by MaxChunksPerEntry
+	truncationMarker = "...[truncated]"
+
+	// chunkEncodingJSON marks chunks of a JSON-encoded reflected field, e.g. one logged with zap.Any
+	chunkEncodingJSON = "json"
+
+	// chunkLineBoundariesEnvVar makes chunks end on line boundaries where possible
+	chunkLineBoundariesEnvVar = "LOG_CHUNK_LINE_BOUNDARIES"
+
+	// maxEntrySizeEnvVar overrides DefaultMaxEntrySize for outputs without WithMaxEntrySize
+	maxEntrySizeEnvVar = "LOG_MAX_ENTRY_BYTES"
+
+	// messageKey is the key of the message in JSON log entries, used for message chunk metadata
+	messageKey = "msg"
+
+	// minChunkSize is the smallest share of an entry given to each chunked value,
+	// below which entries are split anyway even if they stay over the limit
+	minChunkSize = 256
 )
 
-// chunkOversizedFields checks if any string field exceeds MaxStringFieldSize.
-// If so, it returns multiple sets of fields — one per chunk of the oversized
-// field — each annotated with chunk/total metadata. Other oversized string
-// fields beyond the first are truncated as a safety net.
-// Returns nil if no chunking is needed.
-func chunkOversizedFields(fields []zapcore.Field) [][]zapcore.Field {
-	// Find the first oversized string field
-	oversizedIdx := -1
+// WithMaxEntrySize sets the maximum size in bytes of an encoded log entry written to w,
+// to be passed to ConfigureProductionLogger or ConfigureDevelopmentLogger.
+// Larger entries are handled by the OversizePolicy of w, see DefaultMaxEntrySize.
+// A maxBytes of 0 or less disables the limit.
+func WithMaxEntrySize(w io.Writer, maxBytes int) io.Writer {
+	output := newOutputWriter(w)
+	output.maxEntrySize = &maxBytes
+	return output
+}
+
+// outputKey groups the writers sharing a maximum entry size and oversize policy
+type outputKey struct {
+	maxEntrySize int
+	policy       OversizePolicy
+}
+
+// outputCores returns a core per distinct maximum entry size and oversize policy of the writers,
+// and whether any of them needs the context attached to a logger, see contextField
+func outputCores(encoder zapcore.Encoder, writers []io.Writer, naming FieldNaming) (cores []zapcore.Core, passContext bool) {
+	defaultSize := DefaultMaxEntrySize
+	if raw := os.Getenv(maxEntrySizeEnvVar); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			zap.L().Error("failed to parse max entry bytes, using default", zap.Error(err))
+		} else {
+			defaultSize = parsed
+		}
+	}
+
+	defaultPolicy, err := parseOversizePolicy(os.Getenv(oversizePolicyEnvVar))
+	if err != nil {
+		zap.L().Error("failed to parse oversize policy, using chunk", zap.Error(err))
+	}
+
+	var preferLines bool
+	if raw := os.Getenv(chunkLineBoundariesEnvVar); raw != "" {
+		if preferLines, err = strconv.ParseBool(raw); err != nil {
+			zap.L().Error("failed to parse chunk line boundaries, using byte boundaries", zap.Error(err))
+		}
+	}
+
+	var keys []outputKey
+	outputs := map[outputKey][]zapcore.WriteSyncer{}
+	for _, w := range writers {
+		key := outputKey{maxEntrySize: defaultSize, policy: defaultPolicy}
+		if output, ok := w.(*outputWriter); ok {
+			if output.maxEntrySize != nil {
+				key.maxEntrySize = *output.maxEntrySize
+			}
+			if output.policy != nil {
+				key.policy = output.policy
+			}
+			w = output.Writer
+		}
+		if _, ok := outputs[key]; !ok {
+			keys = append(keys, key)
+		}
+		outputs[key] = append(outputs[key], zapcore.AddSync(w))
+	}
+
+	cores = make([]zapcore.Core, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := key.policy.(*offloadPolicy); ok {
+			passContext = true
+		}
+		cores = append(cores, &chunkingCore{
+			LevelEnabler: zapcore.DebugLevel,
+			enc:          encoder.Clone(),
+			out:          zapcore.NewMultiWriteSyncer(outputs[key]...),
+			maxEntrySize: key.maxEntrySize,
+			policy:       key.policy,
+			naming:       naming,
+			preferLines:  preferLines,
+		})
+	}
+	return cores, passContext
+}
+
+// chunkingCore encodes entries and writes them to out. Entries whose encoded size,
+// including the fields added with With, exceeds maxEntrySize are shrunk by policy
+// and split if they are still too large.
+type chunkingCore struct {
+	zapcore.LevelEnabler
+
+	enc          zapcore.Encoder
+	out          zapcore.WriteSyncer
+	maxEntrySize int
+	policy       OversizePolicy
+	naming       FieldNaming
+	preferLines  bool
+}
+
+func (c *chunkingCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return &clone
+}
+
+func (c *chunkingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *chunkingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+
+	size := buf.Len()
+	if c.maxEntrySize > 0 && size > c.maxEntrySize {
+		var shrunk bool
+		ent.Message, fields, shrunk = c.policy.shrink(contextFromFields(fields), ent.Message, fields, size, c.maxEntrySize, c.naming)
+		if shrunk {
+			buf.Free()
+			if buf, err = c.enc.EncodeEntry(ent, fields); err != nil {
+				return err
+			}
+			size = buf.Len()
+		}
+	}
+
+	if c.maxEntrySize <= 0 || size <= c.maxEntrySize {
+		_, err = c.out.Write(buf.Bytes())
+		buf.Free()
+	} else {
+		buf.Free()
+		for _, chunk := range splitEntry(ent.Message, fields, size, c.maxEntrySize, c.naming, c.preferLines) {
+			chunkEnt := ent
+			chunkEnt.Message = chunk.msg
+
+			chunkBuf, encodeErr := c.enc.EncodeEntry(chunkEnt, chunk.fields)
+			if encodeErr != nil {
+				err = errors.Join(err, encodeErr)
+				continue
+			}
+			_, writeErr := c.out.Write(chunkBuf.Bytes())
+			err = errors.Join(err, writeErr)
+			chunkBuf.Free()
+		}
+	}
+
+	if ent.Level > zapcore.ErrorLevel {
+		// flush before a panic or fatal exit, as the io core of zap does
+		_ = c.out.Sync()
+	}
+	return err
+}
+
+func (c *chunkingCore) Sync() error {
+	return c.out.Sync()
+}
+
+// chunkedEntry is one of the log entries an oversized entry is split into
+type chunkedEntry struct {
+	msg    string
+	fields []zapcore.Field
+}
+
+// splittableValue is the message or a string, byte string or reflected field value that can be split
+type splittableValue struct {
+	key      string
+	field    int // index in fields, -1 for the message
+	value    string
+	encoding string
+	// encodedSize is the size of the value in the original encoded entry
+	encodedSize int
+	chunks      []string
+}
+
+// splitEntry splits an entry whose encoded size exceeds maxEntrySize. The largest of the
+// message and the string, byte string or reflected fields are split, until the rest of
+// the entry leaves room for them. The entry is split into as many entries as the most
+// chunks of any split value, up to MaxChunksPerEntry. Entry i holds chunk i of every split
+// value that has one, each annotated with chunk/total metadata and a chunk ID shared by
+// all chunks of the entry, so interleaved entries can be stitched back together.
+// Reflected fields are split as JSON and marked with <key>_chunk_encoding.
+func splitEntry(msg string, fields []zapcore.Field, encodedSize, maxEntrySize int, naming FieldNaming, preferLines bool) []chunkedEntry {
+	split, overhead := selectOversized(msg, fields, encodedSize, maxEntrySize)
+	chunkSize := max((maxEntrySize-overhead)/max(len(split), 1), minChunkSize)
+
+	totalEntries := 1
+	splitFields := map[int]bool{}
+	for i := range split {
+		split[i].chunks = capChunks(chunkString(split[i].value, chunkSize, preferLines))
+		totalEntries = max(totalEntries, len(split[i].chunks))
+		splitFields[split[i].field] = true
+	}
+
+	baseFields := make([]zapcore.Field, 0, len(fields))
 	for i, f := range fields {
-		if f.Type == zapcore.StringType && len(f.String) > MaxStringFieldSize {
-			oversizedIdx = i
-			break
+		if !splitFields[i] {
+			baseFields = append(baseFields, f)
 		}
 	}
 
-	if oversizedIdx == -1 {
-		return nil
+	chunkID := newChunkID()
+
+	result := make([]chunkedEntry, totalEntries)
+	for i := range result {
+		entry := chunkedEntry{msg: msg, fields: make([]zapcore.Field, 0, len(baseFields)+5*len(split))}
+		entry.fields = append(entry.fields, baseFields...)
+		if splitFields[-1] {
+			entry.msg = ""
+		}
+
+		for _, v := range split {
+			if i >= len(v.chunks) {
+				continue
+			}
+
+			if v.field == -1 {
+				entry.msg = v.chunks[i]
+			} else {
+				entry.fields = append(entry.fields, zap.String(v.key, v.chunks[i]))
+			}
+			entry.fields = append(entry.fields,
+				zap.Int(naming.suffixKey(v.key, "_chunk"), i+1),
+				zap.Int(naming.suffixKey(v.key, "_total_chunks"), len(v.chunks)),
+				zap.String(naming.suffixKey(v.key, "_chunk_id"), chunkID),
+			)
+			if v.encoding != "" {
+				entry.fields = append(entry.fields, zap.String(naming.suffixKey(v.key, "_chunk_encoding"), v.encoding))
+			}
+		}
+
+		result[i] = entry
 	}
 
-	oversized := fields[oversizedIdx]
-	chunks := chunkString(oversized.String, MaxStringFieldSize)
-	totalChunks := len(chunks)
+	return result
+}
 
-	// Build base fields: everything except the oversized field.
-	// Truncate any other oversized string fields as a safety measure.
-	baseFields := make([]zapcore.Field, 0, len(fields)-1)
+// selectOversized returns the largest of the message and the string, byte string or reflected
+// fields of an entry, until the rest of the entry and the chunk metadata of each selected value
+// leave at least minChunkSize bytes for each of them, and the encoded size of the entry without them
+func selectOversized(msg string, fields []zapcore.Field, encodedSize, maxEntrySize int) ([]splittableValue, int) {
+	candidates := []splittableValue{{key: messageKey, field: -1, value: msg, encodedSize: escapedLen(msg)}}
 	for i, f := range fields {
-		if i == oversizedIdx {
-			continue
+		if v, ok := splittableField(f); ok {
+			v.field = i
+			candidates = append(candidates, v)
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b splittableValue) int { return cmp.Compare(b.encodedSize, a.encodedSize) })
+
+	var split []splittableValue
+	overhead := encodedSize
+	for _, v := range candidates {
+		if overhead <= maxEntrySize-minChunkSize*len(split) {
+			break
+		}
+		split = append(split, v)
+		overhead += chunkMetadataSize(v) - v.encodedSize
+	}
+
+	return split, overhead
+}
+
+// splittableField returns the value of a string, byte string or reflected field
+func splittableField(f zapcore.Field) (splittableValue, bool) {
+	switch f.Type {
+	case zapcore.StringType:
+		return splittableValue{key: f.Key, value: f.String, encodedSize: escapedLen(f.String)}, true
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			s := string(b)
+			return splittableValue{key: f.Key, value: s, encodedSize: escapedLen(s)}, true
 		}
-		if f.Type == zapcore.StringType && len(f.String) > MaxStringFieldSize {
-			f.String = f.String[:MaxStringFieldSize] + "...[truncated]"
+	case zapcore.ReflectType:
+		if encoded, ok := encodeReflected(f.Interface); ok {
+			return splittableValue{key: f.Key, value: string(encoded), encoding: chunkEncodingJSON, encodedSize: len(encoded)}, true
 		}
-		baseFields = append(baseFields, f)
 	}
+	return splittableValue{}, false
+}
 
-	result := make([][]zapcore.Field, totalChunks)
-	for i, chunk := range chunks {
-		entryFields := make([]zapcore.Field, 0, len(baseFields)+3)
-		entryFields = append(entryFields, baseFields...)
-		entryFields = append(entryFields,
-			zap.String(oversized.Key, chunk),
-			zap.Int(oversized.Key+"_chunk", i+1),
-			zap.Int(oversized.Key+"_total_chunks", totalChunks),
-		)
-		result[i] = entryFields
+// chunkMetadataSize is an upper bound of the encoded size of a chunk of v without its value,
+// i.e. the key and quotes of the value and the chunk metadata fields
+func chunkMetadataSize(v splittableValue) int {
+	size := 4*len(v.key) + 96
+	if v.encoding != "" {
+		size += len(v.key) + 32
 	}
+	return size
+}
 
-	return result
+// encodeReflected encodes v as the JSON encoder of zap would
+func encodeReflected(v any) ([]byte, bool) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, false
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), true
 }
 
-// chunkString splits s into pieces of at most chunkSize bytes.
-func chunkString(s string, chunkSize int) []string {
-	if len(s) <= chunkSize {
-		return []string{s}
+// capChunks keeps the first MaxChunksPerEntry chunks, marking the last one as truncated
+func capChunks(chunks []string) []string {
+	if len(chunks) <= MaxChunksPerEntry {
+		return chunks
 	}
+	chunks = chunks[:MaxChunksPerEntry]
+	chunks[MaxChunksPerEntry-1] += truncationMarker
+	return chunks
+}
 
+// chunkString splits s into pieces of at most chunkSize bytes once escaped as a JSON string.
+// Pieces end on a rune boundary so multi-byte characters are never split; a single rune larger
+// than chunkSize gets a piece of its own. With preferLines, a piece ends after the last newline
+// in its second half, if any. Concatenating the pieces always reproduces s.
+func chunkString(s string, chunkSize int, preferLines bool) []string {
 	var chunks []string
-	for len(s) > chunkSize {
-		chunks = append(chunks, s[:chunkSize])
-		s = s[chunkSize:]
+	for len(s) > 0 {
+		cut := chunkBoundary(s, chunkSize, preferLines)
+		chunks = append(chunks, s[:cut])
+		s = s[cut:]
 	}
-	if len(s) > 0 {
-		chunks = append(chunks, s)
+	if chunks == nil {
+		return []string{""}
 	}
 	return chunks
 }
+
+// chunkBoundary returns the end of the next piece of s
+func chunkBoundary(s string, chunkSize int, preferLines bool) int {
+	size, lastNewline := 0, -1
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		width := escapedRuneLen(r, n)
+
+		if size+width > chunkSize {
+			if i == 0 {
+				return n
+			}
+			if preferLines && lastNewline >= i/2 {
+				return lastNewline + 1
+			}
+			return i
+		}
+
+		if r == '\n' {
+			lastNewline = i
+		}
+		size += width
+		i += n
+	}
+	return len(s)
+}
+
+// escapedLen returns the size of s escaped by the JSON encoder of zap, without quotes
+func escapedLen(s string) int {
+	size := 0
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		size += escapedRuneLen(r, n)
+		i += n
+	}
+	return size
+}
+
+// escapedRuneLen returns the size of a rune of n bytes escaped by the JSON encoder of zap
+func escapedRuneLen(r rune, n int) int {
+	switch {
+	case r == utf8.RuneError && n == 1:
+		return len(`\ufffd`)
+	case r == '\\' || r == '"' || r == '\n' || r == '\r' || r == '\t':
+		return 2
+	case r < 0x20:
+		return len(`\u0000`)
+	default:
+		return n
+	}
+}
+
+// newChunkID returns a random ID grouping the chunks of one log entry
+func newChunkID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
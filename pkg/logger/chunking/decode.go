@@ -0,0 +1,57 @@
+package chunking
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encodingGzipBase64 marks the fields compressed by the logger's CompressOversized policy
+const encodingGzipBase64 = "gzip+base64"
+
+// Decode restores in place the fields of a log line compressed with gzip+base64, marked by a
+// <key>_encoding field in any of the logger field naming conventions, and removes the markers.
+// Fields that fail to decode are left as they are, and their errors are returned joined.
+func Decode(fields map[string]any) error {
+	var errs []error
+	for key, value := range fields {
+		encoded, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		for _, s := range namingSuffixes {
+			if encoding, _ := fields[key+s.valueEncoding].(string); encoding != encodingGzipBase64 {
+				continue
+			}
+
+			decoded, err := gunzipBase64(encoded)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to decode %s: %w", key, err))
+				break
+			}
+			fields[key] = decoded
+			delete(fields, key+s.valueEncoding)
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// gunzipBase64 reverses the gzip+base64 encoding of s
+func gunzipBase64(s string) (string, error) {
+	r, err := gzip.NewReader(base64.NewDecoder(base64.StdEncoding, strings.NewReader(s)))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,69 @@
+package chunking
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBase64(t *testing.T, s string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDecode(t *testing.T) {
+	fields := map[string]any{
+		"msg":             "scan finished",
+		"report":          gzipBase64(t, "compat"),
+		"report_encoding": "gzip+base64",
+		"stack":           gzipBase64(t, "camel"),
+		"stackEncoding":   "gzip+base64",
+		"body":            gzipBase64(t, "otel"),
+		"body.encoding":   "gzip+base64",
+		"other":           "plain",
+		"other_encoding":  "identity",
+	}
+
+	require.NoError(t, Decode(fields))
+	assert.Equal(t, map[string]any{
+		"msg":            "scan finished",
+		"report":         "compat",
+		"stack":          "camel",
+		"body":           "otel",
+		"other":          "plain",
+		"other_encoding": "identity",
+	}, fields)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	fields := map[string]any{"report": "not base64!", "report_encoding": "gzip+base64"}
+
+	assert.ErrorContains(t, Decode(fields), "report")
+	assert.Equal(t, "not base64!", fields["report"])
+	assert.Equal(t, "gzip+base64", fields["report_encoding"])
+}
+
+func TestReassembleDecodes(t *testing.T) {
+	encoded := gzipBase64(t, "hello world")
+	input := strings.Join([]string{
+		`{"msg":"plain","report":"` + gzipBase64(t, "short") + `","report_encoding":"gzip+base64"}`,
+		`{"msg":"chunked","report":"` + encoded[:10] + `","report_encoding":"gzip+base64","report_chunk":1,"report_total_chunks":2,"report_chunk_id":"c1"}`,
+		`{"msg":"chunked","report":"` + encoded[10:] + `","report_encoding":"gzip+base64","report_chunk":2,"report_total_chunks":2,"report_chunk_id":"c1"}`,
+	}, "\n")
+
+	entries := slices.Collect(Reassemble(strings.NewReader(input)))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "short", entries[0].Fields["report"])
+	assert.Equal(t, "hello world", entries[1].Fields["report"])
+	assert.NotContains(t, entries[1].Fields, "report_encoding")
+}
@@ -0,0 +1,264 @@
+// Package chunking reassembles log entries that the logger split into chunks because their encoded size exceeded the maximum entry size of the output, and decodes the fields it compressed.
+package chunking
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// chunkSuffixes are the chunk and encoding metadata key suffixes written by each logger field naming convention
+type chunkSuffixes struct {
+	chunk, total, id, encoding, valueEncoding string
+}
+
+var namingSuffixes = []chunkSuffixes{
+	{chunk: "_chunk", total: "_total_chunks", id: "_chunk_id", encoding: "_chunk_encoding", valueEncoding: "_encoding"}, // compat and snake
+	{chunk: "Chunk", total: "TotalChunks", id: "ChunkId", encoding: "ChunkEncoding", valueEncoding: "Encoding"},         // camel
+	{chunk: ".chunk", total: ".total_chunks", id: ".chunk_id", encoding: ".chunk_encoding", valueEncoding: ".encoding"}, // otel
+}
+
+const (
+	// encodingJSON marks the chunks of a value logged as JSON, e.g. with zap.Any
+	encodingJSON = "json"
+
+	// DefaultPendingLines is the number of lines Reassemble reads after the first chunk line of an
+	// entry before yielding it with its missing chunks
+	DefaultPendingLines = 10000
+)
+
+// Entry is a log line, or a log entry reassembled from its chunks
+type Entry struct {
+	// Fields are the decoded JSON fields. For a reassembled entry the chunked fields hold the
+	// concatenated chunks and the chunk metadata fields are removed. Fields chunked as JSON
+	// are decoded once all their chunks are found.
+	Fields map[string]any
+	// ChunkedKeys are the keys of the fields that were reassembled, sorted, empty if the line was not chunked.
+	// The message is reassembled under msg.
+	ChunkedKeys []string
+	// Missing lists the 1-based numbers of the chunk lines that were not found, in order
+	Missing []int
+}
+
+// Complete reports whether every chunk of the entry was found
+func (e Entry) Complete() bool {
+	return len(e.Missing) == 0
+}
+
+// chunkedField is a field split across the lines of a group
+type chunkedField struct {
+	key      string
+	suffixes chunkSuffixes
+	number   int
+	total    int
+}
+
+// group collects the chunk lines of one entry
+type group struct {
+	id     string
+	line   int
+	total  int
+	fields map[string]any
+	keys   map[string]chunkSuffixes
+	totals map[string]int
+	parts  map[string]map[int]string
+	seen   map[int]bool
+}
+
+func newGroup(id string, line int, fields map[string]any) *group {
+	return &group{
+		id:     id,
+		line:   line,
+		fields: fields,
+		keys:   map[string]chunkSuffixes{},
+		totals: map[string]int{},
+		parts:  map[string]map[int]string{},
+		seen:   map[int]bool{},
+	}
+}
+
+// add records the chunks of one line and reports whether the group is complete
+func (g *group) add(fields map[string]any, chunked []chunkedField) bool {
+	for _, c := range chunked {
+		if _, ok := g.keys[c.key]; !ok {
+			g.keys[c.key] = c.suffixes
+			g.parts[c.key] = map[int]string{}
+		}
+		g.totals[c.key] = max(g.totals[c.key], c.total)
+		g.total = max(g.total, c.total)
+
+		value, _ := fields[c.key].(string)
+		g.parts[c.key][c.number] = value
+		g.seen[c.number] = true
+	}
+	return len(g.seen) >= g.total
+}
+
+func (g *group) entry() Entry {
+	var missing []int
+	for i := 1; i <= g.total; i++ {
+		if !g.seen[i] {
+			missing = append(missing, i)
+		}
+	}
+
+	fields := g.fields
+	keys := make([]string, 0, len(g.keys))
+	for key, suffixes := range g.keys {
+		keys = append(keys, key)
+
+		var value strings.Builder
+		complete := true
+		for i := 1; i <= g.totals[key]; i++ {
+			part, ok := g.parts[key][i]
+			complete = complete && ok
+			value.WriteString(part)
+		}
+
+		fields[key] = value.String()
+		if encoding, _ := fields[key+suffixes.encoding].(string); encoding == encodingJSON && complete {
+			var decoded any
+			if json.Unmarshal([]byte(value.String()), &decoded) == nil {
+				fields[key] = decoded
+			}
+		}
+
+		delete(fields, key+suffixes.chunk)
+		delete(fields, key+suffixes.total)
+		delete(fields, key+suffixes.id)
+		delete(fields, key+suffixes.encoding)
+	}
+	slices.Sort(keys)
+	_ = Decode(fields)
+
+	return Entry{Fields: fields, ChunkedKeys: keys, Missing: missing}
+}
+
+// Reassemble reads JSON log lines from r and yields one Entry per log entry, with compressed
+// fields restored by Decode. Lines that are not chunked are yielded as they are read. Chunked entries are yielded
+// once their last chunk line is read, so chunk lines of concurrent entries may be interleaved.
+// Entries still missing chunks DefaultPendingLines lines after their first chunk line, or at
+// the end of r, are yielded with Missing set, in the order they were first seen. Lines that are
+// not JSON objects are skipped, and reading stops at the first read error.
+//
+// Chunks are grouped by their chunk ID. Chunks written before chunk IDs were added are
+// grouped by message, key and chunk count instead.
+func Reassemble(r io.Reader) iter.Seq[Entry] {
+	return ReassembleWithin(r, DefaultPendingLines)
+}
+
+// ReassembleWithin is Reassemble with the chunk lines of an entry expected within the given
+// number of lines after its first chunk line, DefaultPendingLines if not positive. It bounds
+// the entries held in memory when reading an unbounded stream. A chunk line read after its
+// entry was yielded is reassembled as a separate incomplete entry.
+func ReassembleWithin(r io.Reader, lines int) iter.Seq[Entry] {
+	if lines <= 0 {
+		lines = DefaultPendingLines
+	}
+
+	return func(yield func(Entry) bool) {
+		reader := bufio.NewReader(r)
+		groups := map[string]*group{}
+		var pending []*group
+
+		for number := 1; ; number++ {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				var fields map[string]any
+				if json.Unmarshal(line, &fields) == nil && fields != nil {
+					if !collect(fields, number, groups, &pending, yield) {
+						return
+					}
+				}
+			}
+
+			// pending groups are in the order they were first seen, so the expired ones come first
+			for len(pending) > 0 && number-pending[0].line >= lines {
+				g := pending[0]
+				pending = pending[1:]
+				delete(groups, g.id)
+				if !yield(g.entry()) {
+					return
+				}
+			}
+
+			if err != nil {
+				break
+			}
+		}
+
+		for _, g := range pending {
+			if !yield(g.entry()) {
+				return
+			}
+		}
+	}
+}
+
+// collect yields fields if they are not a chunk line, otherwise adds them to their group and
+// yields the group once complete. It returns false if the consumer stopped.
+func collect(fields map[string]any, line int, groups map[string]*group, pending *[]*group, yield func(Entry) bool) bool {
+	chunked := chunkedFields(fields)
+	if len(chunked) == 0 {
+		_ = Decode(fields)
+		return yield(Entry{Fields: fields})
+	}
+
+	first := chunked[0]
+	id, _ := fields[first.key+first.suffixes.id].(string)
+	if id == "" {
+		msg, _ := fields["msg"].(string)
+		id = strings.Join([]string{"legacy", msg, first.key, strconv.Itoa(first.total)}, "\x00")
+	}
+
+	g, ok := groups[id]
+	if !ok {
+		g = newGroup(id, line, fields)
+		groups[id] = g
+		*pending = append(*pending, g)
+	}
+
+	if !g.add(fields, chunked) {
+		return true
+	}
+
+	delete(groups, id)
+	*pending = slices.DeleteFunc(*pending, func(p *group) bool { return p == g })
+	return yield(g.entry())
+}
+
+// chunkedFields finds the chunked fields of a log line by their chunk number and count fields, sorted by key
+func chunkedFields(fields map[string]any) []chunkedField {
+	var chunked []chunkedField
+	for k, v := range fields {
+		n, isNumber := v.(float64)
+		if !isNumber {
+			continue
+		}
+
+		for _, s := range namingSuffixes {
+			base, found := strings.CutSuffix(k, s.chunk)
+			if !found || base == "" {
+				continue
+			}
+
+			t, isNumber := fields[base+s.total].(float64)
+			if _, isString := fields[base].(string); !isNumber || !isString {
+				continue
+			}
+			if n < 1 || t < n {
+				continue
+			}
+			chunked = append(chunked, chunkedField{key: base, suffixes: s, number: int(n), total: int(t)})
+			break
+		}
+	}
+
+	slices.SortFunc(chunked, func(a, b chunkedField) int { return strings.Compare(a.key, b.key) })
+	return chunked
+}
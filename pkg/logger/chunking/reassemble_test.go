@@ -0,0 +1,148 @@
+package chunking
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nullify-platform/logger/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maxEntrySize keeps the test entries small
+const maxEntrySize = 2000
+
+// logLines logs an entry with an oversized body and returns its JSON lines
+func logLines(t *testing.T, body string) []string {
+	var buf bytes.Buffer
+	ctx, err := logger.ConfigureProductionLogger(t.Context(), "info", logger.WithMaxEntrySize(&buf, maxEntrySize))
+	require.NoError(t, err)
+
+	logger.L(ctx).Info("request", logger.String("body", body), logger.Int("status", 200))
+	lines := strings.SplitAfter(buf.String(), "\n")
+	return lines[:len(lines)-1]
+}
+
+func TestReassembleInterleaved(t *testing.T) {
+	first := strings.Repeat("a", 4500)
+	second := strings.Repeat("b", 2500)
+
+	firstLines := logLines(t, first)
+	secondLines := logLines(t, second)
+	require.Len(t, firstLines, 3)
+	require.Len(t, secondLines, 2)
+
+	input := strings.Join([]string{
+		`{"msg":"before"}` + "\n",
+		firstLines[0], secondLines[0], "not json\n", firstLines[2], secondLines[1], firstLines[1],
+	}, "")
+
+	entries := slices.Collect(Reassemble(strings.NewReader(input)))
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "before", entries[0].Fields["msg"])
+	assert.Empty(t, entries[0].ChunkedKeys)
+
+	assert.Equal(t, []string{"body"}, entries[1].ChunkedKeys)
+	assert.Equal(t, second, entries[1].Fields["body"])
+	assert.True(t, entries[1].Complete())
+
+	assert.Equal(t, first, entries[2].Fields["body"])
+	assert.Equal(t, float64(200), entries[2].Fields["status"])
+	assert.Equal(t, "request", entries[2].Fields["msg"])
+	assert.NotContains(t, entries[2].Fields, "body_chunk")
+	assert.NotContains(t, entries[2].Fields, "body_total_chunks")
+	assert.NotContains(t, entries[2].Fields, "body_chunk_id")
+}
+
+func TestReassembleMissingChunks(t *testing.T) {
+	body := strings.Repeat("x", 4000) + strings.Repeat("y", 500)
+	lines := logLines(t, body)
+	require.Len(t, lines, 3)
+
+	var second map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+
+	entries := slices.Collect(Reassemble(strings.NewReader(lines[0] + lines[2] + `{"msg":"after"}`)))
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "after", entries[0].Fields["msg"])
+	assert.False(t, entries[1].Complete())
+	assert.Equal(t, []int{2}, entries[1].Missing)
+	assert.Equal(t, strings.Replace(body, second["body"].(string), "", 1), entries[1].Fields["body"])
+}
+
+func TestReassembleWithinEvictsIncompleteEntries(t *testing.T) {
+	lines := logLines(t, strings.Repeat("x", 4500))
+	require.Len(t, lines, 3)
+
+	input := strings.Join([]string{
+		lines[0], lines[2], `{"msg":"a"}` + "\n", `{"msg":"b"}` + "\n", `{"msg":"c"}` + "\n", lines[1],
+	}, "")
+
+	var msgs []any
+	var missing [][]int
+	for entry := range ReassembleWithin(strings.NewReader(input), 3) {
+		msgs = append(msgs, entry.Fields["msg"])
+		missing = append(missing, entry.Missing)
+	}
+
+	// the entry is yielded 3 lines after its first chunk line, and its late chunk on its own
+	assert.Equal(t, []any{"a", "b", "request", "c", "request"}, msgs)
+	assert.Equal(t, [][]int{nil, nil, {2}, nil, {1, 3}}, missing)
+}
+
+func TestReassembleLegacyAndNaming(t *testing.T) {
+	input := strings.Join([]string{
+		`{"msg":"legacy","body":"he","body_chunk":1,"body_total_chunks":2}`,
+		`{"msg":"camel","bodyChunkId":"c1","body":"wor","bodyChunk":1,"bodyTotalChunks":2}`,
+		`{"msg":"legacy","body":"llo","body_chunk":2,"body_total_chunks":2}`,
+		`{"msg":"camel","bodyChunkId":"c1","body":"ld","bodyChunk":2,"bodyTotalChunks":2}`,
+		`{"msg":"otel","body.chunk_id":"o1","body":"only","body.chunk":1,"body.total_chunks":1}`,
+	}, "\n")
+
+	entries := slices.Collect(Reassemble(strings.NewReader(input)))
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "hello", entries[0].Fields["body"])
+	assert.Equal(t, "world", entries[1].Fields["body"])
+	assert.NotContains(t, entries[1].Fields, "bodyChunkId")
+	assert.Equal(t, "only", entries[2].Fields["body"])
+}
+
+func TestReassembleStopsEarly(t *testing.T) {
+	input := `{"msg":"one"}` + "\n" + `{"msg":"two"}` + "\n"
+
+	var msgs []any
+	for entry := range Reassemble(strings.NewReader(input)) {
+		msgs = append(msgs, entry.Fields["msg"])
+		break
+	}
+	assert.Equal(t, []any{"one"}, msgs)
+}
+
+func TestReassembleMultipleFields(t *testing.T) {
+	msg := strings.Repeat("m", 1500)
+	body := strings.Repeat("b", 3000)
+	payload := map[string]any{"data": strings.Repeat("p", 1200)}
+
+	var buf bytes.Buffer
+	ctx, err := logger.ConfigureProductionLogger(t.Context(), "info", logger.WithMaxEntrySize(&buf, maxEntrySize))
+	require.NoError(t, err)
+	logger.L(ctx).Info(msg, logger.String("body", body), logger.Any("payload", payload))
+
+	entries := slices.Collect(Reassemble(&buf))
+	require.Len(t, entries, 1)
+
+	entry := entries[0]
+	assert.True(t, entry.Complete())
+	assert.Equal(t, []string{"body", "msg", "payload"}, entry.ChunkedKeys)
+	assert.Equal(t, msg, entry.Fields["msg"])
+	assert.Equal(t, body, entry.Fields["body"])
+	assert.Equal(t, payload, entry.Fields["payload"])
+	assert.NotContains(t, entry.Fields, "payload_chunk_encoding")
+	assert.NotContains(t, entry.Fields, "msg_chunk")
+}
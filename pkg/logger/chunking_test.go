@@ -1,13 +1,16 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
 func TestChunkString(t *testing.T) {
@@ -15,6 +18,7 @@ func TestChunkString(t *testing.T) {
 		name      string
 		input     string
 		chunkSize int
+		lines     bool
 		expected  []string
 	}{
 		{
@@ -41,104 +45,296 @@ func TestChunkString(t *testing.T) {
 			chunkSize: 100,
 			expected:  []string{""},
 		},
+		{
+			name:      "multi-byte runes are not split",
+			input:     "héllo wörld",
+			chunkSize: 2,
+			expected:  []string{"h", "é", "ll", "o ", "w", "ö", "rl", "d"},
+		},
+		{
+			name:      "four-byte runes are not split",
+			input:     "a😀b",
+			chunkSize: 4,
+			expected:  []string{"a", "😀", "b"},
+		},
+		{
+			name:      "invalid UTF-8 is sized as escaped replacement characters",
+			input:     "\x80\x80\x80\x80\x80",
+			chunkSize: 12,
+			expected:  []string{"\x80\x80", "\x80\x80", "\x80"},
+		},
+		{
+			name:      "escaped characters count their escaped size",
+			input:     `a"b"c`,
+			chunkSize: 3,
+			expected:  []string{`a"`, `b"`, "c"},
+		},
+		{
+			name:      "line boundaries are preferred",
+			input:     "line one\nline two\nline three",
+			chunkSize: 12,
+			lines:     true,
+			expected:  []string{"line one\n", "line two\n", "line three"},
+		},
+		{
+			name:      "newlines in the first half are ignored",
+			input:     "a\nbcdefghij",
+			chunkSize: 6,
+			lines:     true,
+			expected:  []string{"a\nbcd", "efghij"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := chunkString(tt.input, tt.chunkSize)
+			result := chunkString(tt.input, tt.chunkSize, tt.lines)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
-func TestChunkOversizedFields_NoChunking(t *testing.T) {
-	fields := []zapcore.Field{
-		zap.String("msg", "short message"),
-		zap.Int("status", 200),
-	}
+// logChunked logs through a production logger writing to an output limited to maxEntrySize
+func logChunked(t *testing.T, maxEntrySize int, log func(l Logger)) []map[string]any {
+	t.Helper()
 
-	result := chunkOversizedFields(fields)
-	assert.Nil(t, result, "should return nil when no fields exceed limit")
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", WithMaxEntrySize(&buf, maxEntrySize))
+	require.NoError(t, err)
+	log(L(ctx))
+	return decodeLogLines(t, &buf)
 }
 
-func TestChunkOversizedFields_SingleOversizedField(t *testing.T) {
-	largeBody := strings.Repeat("x", MaxStringFieldSize*2+100)
-
-	fields := []zapcore.Field{
-		zap.String("status", "error"),
-		zap.String("body", largeBody),
-		zap.Int("code", 500),
-	}
+func TestMaxEntrySize_NoChunking(t *testing.T) {
+	entries := logChunked(t, 1000, func(l Logger) {
+		l.Info("short message", zap.Int("status", 200))
+	})
+	require.Len(t, entries, 1)
+	assert.NotContains(t, entries[0], "msg_chunk")
+}
 
-	chunks := chunkOversizedFields(fields)
-	require.NotNil(t, chunks)
-	assert.Len(t, chunks, 3, "should produce 3 chunks")
+func TestMaxEntrySize_Disabled(t *testing.T) {
+	body := strings.Repeat("x", 5000)
 
-	// Each chunk should contain the base fields + chunk of body + metadata
-	for i, chunkFields := range chunks {
-		fieldMap := fieldsToMap(chunkFields)
+	entries := logChunked(t, 0, func(l Logger) {
+		l.Info("request", zap.String("body", body))
+	})
+	require.Len(t, entries, 1)
+	assert.Equal(t, body, entries[0]["body"])
+}
 
-		// Base fields present in every chunk
-		assert.Equal(t, "error", fieldMap["status"])
-		assert.Equal(t, int64(500), fieldMap["code"])
+func TestMaxEntrySize_SingleOversizedField(t *testing.T) {
+	const limit = 2000
+	body := strings.Repeat("x", 4500)
 
-		// Chunk metadata
-		assert.Equal(t, int64(i+1), fieldMap["body_chunk"])
-		assert.Equal(t, int64(3), fieldMap["body_total_chunks"])
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", WithMaxEntrySize(&buf, limit))
+	require.NoError(t, err)
+	L(ctx).Info("request", zap.String("status", "error"), zap.String("body", body), zap.Int("code", 500))
 
-		// Body chunk is present and within limit
-		bodyChunk := fieldMap["body"].(string)
-		assert.LessOrEqual(t, len(bodyChunk), MaxStringFieldSize)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line)+1, limit, "every encoded entry is within the limit")
 	}
 
-	// Reassemble and verify full content
+	entries := decodeLogLines(t, &buf)
+	chunkID := entries[0]["body_chunk_id"]
+	assert.Len(t, chunkID, 16)
+
 	var reassembled strings.Builder
-	for _, chunkFields := range chunks {
-		fieldMap := fieldsToMap(chunkFields)
-		reassembled.WriteString(fieldMap["body"].(string))
+	for i, entry := range entries {
+		assert.Equal(t, "request", entry["msg"])
+		assert.Equal(t, "error", entry["status"])
+		assert.Equal(t, float64(500), entry["code"])
+		assert.Contains(t, entry, "service.version")
+
+		assert.Equal(t, float64(i+1), entry["body_chunk"])
+		assert.Equal(t, float64(3), entry["body_total_chunks"])
+		assert.Equal(t, chunkID, entry["body_chunk_id"])
+		reassembled.WriteString(entry["body"].(string))
+	}
+	assert.Equal(t, body, reassembled.String())
+}
+
+func TestMaxEntrySize_LineBoundariesPerLogger(t *testing.T) {
+	body := strings.Repeat("a line of the report, 36 bytes long\n", 120)
+
+	var buf bytes.Buffer
+	t.Setenv(chunkLineBoundariesEnvVar, "true")
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", WithMaxEntrySize(&buf, 2000))
+	require.NoError(t, err)
+
+	// configuring another logger does not change how the first one chunks
+	t.Setenv(chunkLineBoundariesEnvVar, "false")
+	_, err = ConfigureProductionLogger(t.Context(), "info", io.Discard)
+	require.NoError(t, err)
+
+	L(ctx).Info("report", zap.String("body", body))
+
+	entries := decodeLogLines(t, &buf)
+	require.Greater(t, len(entries), 1)
+	for _, entry := range entries[:len(entries)-1] {
+		assert.True(t, strings.HasSuffix(entry["body"].(string), "\n"), "chunks end after a newline")
 	}
-	assert.Equal(t, largeBody, reassembled.String())
 }
 
-func TestChunkOversizedFields_MultipleOversized(t *testing.T) {
-	// First oversized field gets chunked, second gets truncated
-	largeBody := strings.Repeat("a", MaxStringFieldSize+500)
-	largeResponse := strings.Repeat("b", MaxStringFieldSize+300)
+func TestMaxEntrySize_CountsEscaping(t *testing.T) {
+	const limit = 2000
+	// each quote is escaped to two bytes, so the field is oversized although its raw size is under the limit
+	body := strings.Repeat(`"`, 1500)
 
-	fields := []zapcore.Field{
-		zap.String("body", largeBody),
-		zap.String("response", largeResponse),
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", WithMaxEntrySize(&buf, limit))
+	require.NoError(t, err)
+	L(ctx).Info("request", zap.String("body", body))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Greater(t, len(lines), 1)
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line)+1, limit)
 	}
+}
 
-	chunks := chunkOversizedFields(fields)
-	require.NotNil(t, chunks)
-	assert.Len(t, chunks, 2, "first field splits into 2 chunks")
+func TestMaxEntrySize_MultipleOversized(t *testing.T) {
+	// the largest values are chunked until the rest of the entry fits, sharing the entry size
+	body := strings.Repeat("a", 3000)
+	response := strings.Repeat("b", 1500)
 
-	// The second oversized field (response) should be truncated in each chunk
-	for _, chunkFields := range chunks {
-		fieldMap := fieldsToMap(chunkFields)
-		response := fieldMap["response"].(string)
-		assert.Contains(t, response, "...[truncated]")
-		assert.LessOrEqual(t, len(response), MaxStringFieldSize+len("...[truncated]"))
+	entries := logChunked(t, 2000, func(l Logger) {
+		l.Info("request", zap.String("body", body), zap.String("response", response), zap.String("status", "ok"))
+	})
+	require.Greater(t, len(entries), 1)
+
+	var bodyValue, responseValue strings.Builder
+	for _, entry := range entries {
+		assert.Equal(t, "ok", entry["status"])
+		assert.Equal(t, entries[0]["body_chunk_id"], entry["body_chunk_id"])
+		bodyValue.WriteString(entry["body"].(string))
+		if value, ok := entry["response"]; ok {
+			assert.Equal(t, entries[0]["body_chunk_id"], entry["response_chunk_id"])
+			responseValue.WriteString(value.(string))
+		}
 	}
+
+	assert.Equal(t, body, bodyValue.String())
+	assert.Equal(t, response, responseValue.String())
 }
 
-func TestChunkOversizedFields_ExactLimit(t *testing.T) {
-	exactBody := strings.Repeat("x", MaxStringFieldSize)
+func TestMaxEntrySize_ByteStringAndReflected(t *testing.T) {
+	stack := []byte(strings.Repeat("s", 3000))
+	payload := map[string]any{"items": []string{strings.Repeat("<i>", 1000)}}
 
-	fields := []zapcore.Field{
-		zap.String("body", exactBody),
+	entries := logChunked(t, 2000, func(l Logger) {
+		l.Info("request", zap.ByteString("stack", stack), zap.Any("payload", payload))
+	})
+	require.Greater(t, len(entries), 1)
+
+	var stackValue, payloadValue strings.Builder
+	for _, entry := range entries {
+		if value, ok := entry["stack"]; ok {
+			stackValue.WriteString(value.(string))
+			assert.NotContains(t, entry, "stack_chunk_encoding")
+		}
+		if value, ok := entry["payload"]; ok {
+			payloadValue.WriteString(value.(string))
+			assert.Equal(t, "json", entry["payload_chunk_encoding"])
+		}
 	}
 
-	result := chunkOversizedFields(fields)
-	assert.Nil(t, result, "exactly at limit should not trigger chunking")
+	assert.Equal(t, string(stack), stackValue.String())
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal([]byte(payloadValue.String()), &decoded))
+	assert.Equal(t, map[string]any{"items": []any{payload["items"].([]string)[0]}}, decoded)
 }
 
-// fieldsToMap converts a slice of zap fields to a map for easier test assertions.
-func fieldsToMap(fields []zapcore.Field) map[string]any {
-	enc := zapcore.NewMapObjectEncoder()
-	for _, f := range fields {
-		f.AddTo(enc)
+func TestMaxEntrySize_Message(t *testing.T) {
+	msg := strings.Repeat("m", 3000)
+
+	entries := logChunked(t, 2000, func(l Logger) {
+		l.Info(msg, zap.String("status", "ok"))
+	})
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, msg, entries[0]["msg"].(string)+entries[1]["msg"].(string))
+	for i, entry := range entries {
+		assert.Equal(t, "ok", entry["status"])
+		assert.Equal(t, float64(i+1), entry["msg_chunk"])
+		assert.Equal(t, float64(2), entry["msg_total_chunks"])
 	}
-	return enc.Fields
+}
+
+func TestMaxEntrySize_MaxChunks(t *testing.T) {
+	body := strings.Repeat("x", 2000*(MaxChunksPerEntry+2))
+
+	entries := logChunked(t, 2000, func(l Logger) {
+		l.Info("request", zap.String("body", body))
+	})
+	require.Len(t, entries, MaxChunksPerEntry)
+
+	last := entries[MaxChunksPerEntry-1]
+	assert.Equal(t, float64(MaxChunksPerEntry), last["body_total_chunks"])
+	assert.True(t, strings.HasSuffix(last["body"].(string), "...[truncated]"))
+}
+
+func TestMaxEntrySize_DistinctChunkIDs(t *testing.T) {
+	body := strings.Repeat("x", 3000)
+
+	entries := logChunked(t, 2000, func(l Logger) {
+		l.Info("request", zap.String("body", body))
+		l.Info("request", zap.String("body", body))
+	})
+	require.Len(t, entries, 4)
+	assert.NotEqual(t, entries[0]["body_chunk_id"], entries[2]["body_chunk_id"])
+}
+
+func TestMaxEntrySize_FieldNaming(t *testing.T) {
+	t.Setenv(fieldNamingEnvVar, string(FieldNamingCamel))
+
+	entries := logChunked(t, 2000, func(l Logger) {
+		l.Info("request", zap.String("body", strings.Repeat("x", 3000)), zap.Int("file_chunk", 7))
+	})
+	require.Len(t, entries, 2)
+	assert.Equal(t, float64(7), entries[0]["file_chunk"])
+	assert.Equal(t, float64(1), entries[0]["bodyChunk"])
+	assert.Equal(t, float64(2), entries[0]["bodyTotalChunks"])
+	assert.Contains(t, entries[0], "bodyChunkId")
+}
+
+func TestMaxEntrySize_PerOutput(t *testing.T) {
+	t.Setenv(maxEntrySizeEnvVar, "100000")
+
+	var limited, unlimited bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", WithMaxEntrySize(&limited, 2000), &unlimited)
+	require.NoError(t, err)
+	L(ctx).Info("request", zap.String("body", strings.Repeat("x", 3000)))
+
+	assert.Len(t, decodeLogLines(t, &limited), 2)
+	assert.Len(t, decodeLogLines(t, &unlimited), 1)
+}
+
+func FuzzChunkString(f *testing.F) {
+	f.Add("hello world", uint16(3), false)
+	f.Add("héllo wörld 😀 日本語", uint16(4), false)
+	f.Add("line one\nline two\n\nline three", uint16(7), true)
+	f.Add("\xff\xfe\x80\x80\x80\x80abc", uint16(2), true)
+
+	f.Fuzz(func(t *testing.T, s string, size uint16, lines bool) {
+		chunkSize := int(size%64) + 1
+
+		chunks := chunkString(s, chunkSize, lines)
+		require.Equal(t, s, strings.Join(chunks, ""), "chunks must reproduce the input byte-for-byte")
+
+		for i, chunk := range chunks {
+			if utf8.RuneCountInString(chunk) > 1 {
+				require.LessOrEqual(t, escapedLen(chunk), chunkSize, "chunk %d exceeds the budget once escaped", i)
+			}
+			if len(s) > 0 {
+				require.NotEmpty(t, chunk)
+			}
+			if utf8.ValidString(s) {
+				require.True(t, utf8.ValidString(chunk), "chunk %d of valid UTF-8 is invalid: %q", i, chunk)
+			}
+		}
+	})
 }
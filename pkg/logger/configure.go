@@ -5,11 +5,13 @@ import (
 	"io"
 	"os"
 	"runtime/debug"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
 	"github.com/nullify-platform/logger/pkg/logger/meter"
 	"github.com/nullify-platform/logger/pkg/logger/tracer"
 	"go.opentelemetry.io/otel"
@@ -91,25 +93,34 @@ func configureLogger(ctx context.Context, level, scopeName string, encoder zapco
 		writers = []io.Writer{os.Stdout}
 	}
 
-	// Convert io.Writers to zapcore.WriteSyncers
-	writeSyncers := make([]zapcore.WriteSyncer, len(writers))
-	for i, writer := range writers {
-		writeSyncers[i] = zapcore.AddSync(writer)
-	}
-
-	// Combine multiple syncs into a single WriteSyncer
-	multiSync := zapcore.NewMultiWriteSyncer(writeSyncers...)
-
 	version := Version
 	if version == "" {
 		version = BuildInfoRevision
 	}
 
-	defaultFields := []zapcore.Field{zap.String("service.version", version)}
+	defaultFields := []zapcore.Field{libraryfield.Mark(zap.String("service.version", version))}
 	defaultFields = append(defaultFields, otelEnvFields()...)
 
+	// configure field naming
+	naming, err := ParseFieldNaming(os.Getenv(fieldNamingEnvVar))
+	if err != nil {
+		zap.L().Error("failed to parse field naming, using compat", zap.Error(err))
+	}
+
+	spanAttributeNaming, err := ParseSpanAttributeNaming(os.Getenv(spanAttributeNamingEnvVar))
+	if err != nil {
+		zap.L().Error("failed to parse span attribute naming, using both", zap.Error(err))
+	}
+
+	loadTenantOverridesFromEnv()
+
+	// each maximum entry size gets its own core, as oversized entries are split per output
+	cores, passContext := outputCores(encoder, writers, naming)
+	core := zapcore.NewTee(cores...)
+
+	// the level is applied by levelCore so tenant overrides can lower it per logger
 	zapLogger := zap.New(
-		zapcore.NewCore(encoder, multiSync, zapLevel),
+		&levelCore{Core: &hookCore{Core: newNamingCore(core, naming)}, level: zapLevel},
 		zap.AddCaller(),
 		zap.AddCallerSkip(1),
 		zap.Fields(defaultFields...),
@@ -121,7 +132,7 @@ func configureLogger(ctx context.Context, level, scopeName string, encoder zapco
 		return nil, err
 	}
 
-	l := &logger{underlyingLogger: zapLogger}
+	l := &logger{underlyingLogger: zapLogger, passContext: passContext, spanAttributeNaming: spanAttributeNaming}
 	ctx = l.InjectIntoContext(ctx)
 	return ctx, nil
 }
@@ -150,10 +161,14 @@ func configureOTel(ctx context.Context, scopeName string) (context.Context, erro
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(traceExporter),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSampler(TenantOverrideSampler(samplerFromEnv())),
 	)
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if logConfigPropagator := logConfigPropagatorFromEnv(); logConfigPropagator != nil {
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, logConfigPropagator))
+	} else {
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	}
 	ctx = tracer.NewContext(ctx, tp, scopeName+"-tracer")
 
 	metricExporter, err := newMetricExporter(ctx, headers)
@@ -172,6 +187,41 @@ func configureOTel(ctx context.Context, scopeName string) (context.Context, erro
 	return ctx, nil
 }
 
+// samplerFromEnv returns the sampler selected by OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG,
+// always_on by default
+func samplerFromEnv() sdktrace.Sampler {
+	ratio := func() float64 {
+		raw := os.Getenv("OTEL_TRACES_SAMPLER_ARG")
+		if raw == "" {
+			return 1
+		}
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			zap.L().Error("failed to parse trace sampler ratio, using 1", zap.String("ratio", raw))
+			return 1
+		}
+		return parsed
+	}
+
+	switch name := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER"))); name {
+	case "", "always_on":
+		return sdktrace.AlwaysSample()
+	case "always_off":
+		return sdktrace.NeverSample()
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio())
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample())
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio()))
+	case "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	default:
+		zap.L().Error("unsupported trace sampler, using always_on", zap.String("sampler", name))
+		return sdktrace.AlwaysSample()
+	}
+}
+
 // resolveOTLPHeaders fetches OTLP exporter headers once from SSM parameter store.
 // Returns nil if no endpoint is configured or no headers are needed.
 func resolveOTLPHeaders(ctx context.Context) map[string]string {
@@ -240,7 +290,7 @@ func otelEnvFields() []zapcore.Field {
 	var fields []zapcore.Field
 
 	if serviceName := os.Getenv("OTEL_SERVICE_NAME"); serviceName != "" {
-		fields = append(fields, zap.String("service.name", serviceName))
+		fields = append(fields, libraryfield.Mark(zap.String("service.name", serviceName)))
 	}
 
 	if attrs := os.Getenv("OTEL_RESOURCE_ATTRIBUTES"); attrs != "" {
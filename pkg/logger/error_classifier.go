@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/aws/smithy-go"
+)
+
+// ErrorClassifier returns the ErrorType of err and true if it recognises the error
+type ErrorClassifier func(err error) (ErrorType, bool)
+
+var (
+	errorClassifiersMu sync.RWMutex
+	errorClassifiers   []ErrorClassifier
+)
+
+// RegisterErrorClassifier registers a service-specific classifier that is consulted
+// by ClassifyError before the built-in rules, in registration order
+func RegisterErrorClassifier(classifier ErrorClassifier) {
+	errorClassifiersMu.Lock()
+	defer errorClassifiersMu.Unlock()
+	errorClassifiers = append(errorClassifiers, classifier)
+}
+
+// ClassifyError inspects the error chain of err and returns the matching ErrorType.
+// Registered classifiers take precedence over the built-in rules, which recognise
+// context deadlines, net.Error timeouts, URL and DNS errors, AWS API errors and
+// JSON decoding errors. ErrorTypeUnknown is returned if nothing matches.
+func ClassifyError(err error) ErrorType {
+	if err == nil {
+		return ErrorTypeUnknown
+	}
+
+	errorClassifiersMu.RLock()
+	classifiers := errorClassifiers
+	errorClassifiersMu.RUnlock()
+
+	for _, classifier := range classifiers {
+		if errType, ok := classifier(err); ok {
+			return errType
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrorTypeTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTypeTimeout
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return classifyAPIError(apiErr)
+	}
+
+	var dnsErr *net.DNSError
+	var urlErr *url.Error
+	var opErr *net.OpError
+	if errors.As(err, &dnsErr) || errors.As(err, &urlErr) || errors.As(err, &opErr) {
+		return ErrorTypeNetwork
+	}
+
+	var syntaxErr *json.SyntaxError
+	var unmarshalTypeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &unmarshalTypeErr) {
+		return ErrorTypeValidation
+	}
+
+	return ErrorTypeUnknown
+}
+
+// classifyAPIError maps AWS API error codes onto an ErrorType
+func classifyAPIError(apiErr smithy.APIError) ErrorType {
+	code := apiErr.ErrorCode()
+
+	switch {
+	case strings.Contains(code, "Timeout"):
+		return ErrorTypeTimeout
+	case strings.Contains(code, "Validation"),
+		strings.HasPrefix(code, "InvalidParameter"),
+		strings.HasPrefix(code, "InvalidInput"),
+		strings.HasPrefix(code, "Malformed"):
+		return ErrorTypeValidation
+	case strings.HasPrefix(code, "AccessDenied"),
+		strings.HasPrefix(code, "UnrecognizedClient"),
+		strings.HasPrefix(code, "InvalidClientTokenId"),
+		strings.HasPrefix(code, "ExpiredToken"):
+		return ErrorTypeConfig
+	case apiErr.ErrorFault() == smithy.FaultServer:
+		return ErrorTypeSystem
+	default:
+		return ErrorTypeUnknown
+	}
+}
+
+// ErrorFrom adds error-related fields to the log entry with the type
+// classified by ClassifyError, the error message, and a traceback.
+// Errors that format with extra detail under %+v (e.g. wrapped stack traces)
+// use that as the traceback, otherwise the current goroutine stack is used.
+func ErrorFrom(err error) []Field {
+	if err == nil {
+		return nil
+	}
+
+	return WithErrorInfo(errorFieldsFrom(err))
+}
+
+func errorFieldsFrom(err error) ErrorFields {
+	traceback := fmt.Sprintf("%+v", err)
+	if traceback == err.Error() {
+		traceback = string(debug.Stack())
+	}
+
+	return ErrorFields{
+		Type:         ClassifyError(err),
+		Message:      err.Error(),
+		Traceback:    traceback,
+		tracebackSet: true,
+	}
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	syntaxErr, _ := json.Unmarshal([]byte("{"), &struct{}{}).(*json.SyntaxError)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorType
+	}{
+		{"nil", nil, ErrorTypeUnknown},
+		{"plain error", errors.New("boom"), ErrorTypeUnknown},
+		{"context deadline", fmt.Errorf("wrapped: %w", context.DeadlineExceeded), ErrorTypeTimeout},
+		{"os deadline", os.ErrDeadlineExceeded, ErrorTypeTimeout},
+		{"net timeout", &net.OpError{Op: "read", Err: timeoutError{}}, ErrorTypeTimeout},
+		{"url timeout", &url.Error{Op: "Get", URL: "https://example.com", Err: timeoutError{}}, ErrorTypeTimeout},
+		{"url error", &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("connection refused")}, ErrorTypeNetwork},
+		{"dns error", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, ErrorTypeNetwork},
+		{"aws validation", &smithy.GenericAPIError{Code: "ValidationException"}, ErrorTypeValidation},
+		{"aws access denied", &smithy.GenericAPIError{Code: "AccessDeniedException"}, ErrorTypeConfig},
+		{"aws timeout", &smithy.GenericAPIError{Code: "RequestTimeout"}, ErrorTypeTimeout},
+		{"aws server fault", &smithy.GenericAPIError{Code: "InternalError", Fault: smithy.FaultServer}, ErrorTypeSystem},
+		{"aws wrapped", &smithy.OperationError{ServiceID: "SQS", Err: &smithy.GenericAPIError{Code: "InvalidParameterValue"}}, ErrorTypeValidation},
+		{"json syntax", fmt.Errorf("decode: %w", syntaxErr), ErrorTypeValidation},
+		{"json type", &json.UnmarshalTypeError{Value: "string"}, ErrorTypeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ClassifyError(tt.err))
+		})
+	}
+}
+
+func TestRegisterErrorClassifier(t *testing.T) {
+	original := errorClassifiers
+	t.Cleanup(func() { errorClassifiers = original })
+
+	errScanFailed := errors.New("scan failed")
+	RegisterErrorClassifier(func(err error) (ErrorType, bool) {
+		if errors.Is(err, errScanFailed) {
+			return ErrorTypeScan, true
+		}
+		return "", false
+	})
+
+	assert.Equal(t, ErrorTypeScan, ClassifyError(fmt.Errorf("semgrep: %w", errScanFailed)))
+	assert.Equal(t, ErrorTypeTimeout, ClassifyError(context.DeadlineExceeded))
+}
+
+func TestErrorFrom(t *testing.T) {
+	assert.Nil(t, ErrorFrom(nil))
+
+	fields := ErrorFrom(fmt.Errorf("fetching repo: %w", context.DeadlineExceeded))
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	assert.Equal(t, "timeout_error", enc.Fields["error_type"])
+	assert.Equal(t, "fetching repo: context deadline exceeded", enc.Fields["error_message"])
+	assert.Contains(t, enc.Fields["error_traceback"], "TestErrorFrom")
+}
@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"context"
 	"time"
 
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -166,7 +168,7 @@ func WithAgent(agent AgentFields) Field {
 		fields["trace_id"] = agent.TraceID
 	}
 
-	return Any("agent", fields)
+	return libraryfield.MarkObject(Any("agent", fields))
 }
 
 // Add this method to AgentFields
@@ -188,7 +190,7 @@ func WithRepository(repo RepositoryFields) Field {
 		fields["owner"] = repo.Owner
 	}
 
-	return Any("repository", fields)
+	return libraryfield.MarkObject(Any("repository", fields))
 }
 
 // WithService adds service-related fields to the log entry
@@ -207,18 +209,18 @@ func WithService(service ServiceFields) Field {
 		fields["category"] = service.Category
 	}
 
-	return Any("service", fields)
+	return libraryfield.MarkObject(Any("service", fields))
 }
 
 // WithErrorInfo adds error-related fields to the log entry
 func WithErrorInfo(errFields ErrorFields) []Field {
 	fields := []Field{
-		String("error_type", string(errFields.Type)),
-		String("error_message", errFields.Message),
+		libraryfield.Mark(String("error_type", string(errFields.Type))),
+		libraryfield.Mark(String("error_message", errFields.Message)),
 	}
 
 	if errFields.tracebackSet {
-		fields = append(fields, String("error_traceback", errFields.Traceback))
+		fields = append(fields, libraryfield.Mark(String("error_traceback", errFields.Traceback)))
 	}
 
 	return fields
@@ -237,7 +239,7 @@ func WithToolCall(toolCall ToolCallFields) Field {
 		fields["duration_ms"] = toolCall.Duration
 	}
 
-	return Any("tool_call", fields)
+	return libraryfield.MarkObject(Any("tool_call", fields))
 }
 
 // Builder methods for ToolCallFields
@@ -262,6 +264,12 @@ type LogFields struct {
 	Service    *ServiceFields
 	Error      *ErrorFields
 	ToolCall   *ToolCallFields
+	LLMCall    *LLMCallFields
+	Finding    *FindingFields
+
+	// contexts of the meters the LLM call and finding metrics are recorded with
+	llmCallCtx context.Context
+	findingCtx context.Context
 }
 
 // NewLogFields creates a new LogFields instance
@@ -356,6 +364,16 @@ func (l *LogFields) WithErrorTraceback(traceback string) *LogFields {
 	return l
 }
 
+// WithErrorFrom adds error fields classified and populated from err
+func (l *LogFields) WithErrorFrom(err error) *LogFields {
+	if err == nil {
+		return l
+	}
+	errFields := errorFieldsFrom(err)
+	l.Error = &errFields
+	return l
+}
+
 func (l *LogFields) WithToolCallInfo(toolName, status string) *LogFields {
 	l.ToolCall = &ToolCallFields{
 		ToolName: toolName,
@@ -380,6 +398,32 @@ func (l *LogFields) WithToolCallDuration(durationMs int64) *LogFields {
 	return l
 }
 
+// WithLLMCall adds LLM call fields. Once an entry with the fields is logged, GenAI attributes
+// are set on the current span and token and latency metrics are recorded using the meter from the context.
+// They are recorded only by loggers configured by ConfigureProductionLogger or ConfigureDevelopmentLogger,
+// and once per logged entry, so logging the fields of one Build() twice records the call twice.
+// Use RecordLLMCall to record a call without logging it, or with another logger.
+func (l *LogFields) WithLLMCall(ctx context.Context, call *LLMCallFields) *LogFields {
+	if call == nil {
+		return l
+	}
+	l.LLMCall = call
+	l.llmCallCtx = ctx
+	return l
+}
+
+// WithFinding adds security finding fields. Once an entry with the fields is logged,
+// the finding counter is incremented using the meter from the context. As with WithLLMCall, it is
+// incremented only by configured loggers, once per logged entry. Use RecordFinding otherwise.
+func (l *LogFields) WithFinding(ctx context.Context, finding *FindingFields) *LogFields {
+	if finding == nil {
+		return l
+	}
+	l.Finding = finding
+	l.findingCtx = ctx
+	return l
+}
+
 // Build creates all the fields based on what was set
 func (l *LogFields) Build() []Field {
 	var fields []Field
@@ -404,5 +448,15 @@ func (l *LogFields) Build() []Field {
 		fields = append(fields, WithToolCall(*l.ToolCall))
 	}
 
+	if l.LLMCall != nil {
+		call, ctx := *l.LLMCall, l.llmCallCtx
+		fields = append(fields, WithLLMCall(call), onWrite(func() { RecordLLMCall(ctx, call) }))
+	}
+
+	if l.Finding != nil {
+		finding, ctx := *l.Finding, l.findingCtx
+		fields = append(fields, WithFinding(finding), onWrite(func() { RecordFinding(ctx, finding) }))
+	}
+
 	return fields
 }
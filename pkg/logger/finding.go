@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
+	"github.com/nullify-platform/logger/pkg/logger/meter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MaxFindingSnippetSize is the maximum size in bytes of a code snippet attached to a finding
+const MaxFindingSnippetSize = 1024
+
+// redactedSnippet replaces the snippet of findings that contain secrets
+const redactedSnippet = "[REDACTED]"
+
+// FindingSeverity represents the severity of a security finding
+type FindingSeverity string
+
+const (
+	FindingSeverityCritical FindingSeverity = "critical"
+	FindingSeverityHigh     FindingSeverity = "high"
+	FindingSeverityMedium   FindingSeverity = "medium"
+	FindingSeverityLow      FindingSeverity = "low"
+	FindingSeverityInfo     FindingSeverity = "info"
+)
+
+// FindingFields represents security finding-related logging fields
+type FindingFields struct {
+	RuleID         string
+	Severity       FindingSeverity
+	Tool           string
+	CWE            string // optional
+	CVE            string // optional
+	Confidence     string // optional
+	FilePath       string // optional
+	StartLine      int    // optional
+	EndLine        int    // optional
+	Fingerprint    string // optional
+	Snippet        string // optional
+	cweSet         bool   // internal tracking
+	cveSet         bool
+	confidenceSet  bool
+	locationSet    bool
+	fingerprintSet bool
+	snippetSet     bool
+	snippetSecret  bool
+}
+
+// Builder methods for FindingFields
+
+// WithCWE adds the optional CWE identifier, e.g. CWE-89
+func (f *FindingFields) WithCWE(cwe string) *FindingFields {
+	f.CWE = cwe
+	f.cweSet = true
+	return f
+}
+
+// WithCVE adds the optional CVE identifier, e.g. CVE-2021-44228
+func (f *FindingFields) WithCVE(cve string) *FindingFields {
+	f.CVE = cve
+	f.cveSet = true
+	return f
+}
+
+// WithConfidence adds the optional confidence reported by the tool
+func (f *FindingFields) WithConfidence(confidence string) *FindingFields {
+	f.Confidence = confidence
+	f.confidenceSet = true
+	return f
+}
+
+// WithLocation adds the optional file path and line range of the finding
+func (f *FindingFields) WithLocation(filePath string, startLine, endLine int) *FindingFields {
+	f.FilePath = filePath
+	f.StartLine = startLine
+	f.EndLine = endLine
+	f.locationSet = true
+	return f
+}
+
+// WithFingerprint adds the optional fingerprint used to deduplicate the finding
+func (f *FindingFields) WithFingerprint(fingerprint string) *FindingFields {
+	f.Fingerprint = fingerprint
+	f.fingerprintSet = true
+	return f
+}
+
+// WithSnippet adds an optional code snippet, truncated to MaxFindingSnippetSize.
+// The snippet is logged verbatim, use WithSecretSnippet for snippets that contain a secret.
+func (f *FindingFields) WithSnippet(snippet string) *FindingFields {
+	f.Snippet = snippet
+	f.snippetSet = true
+	f.snippetSecret = false
+	return f
+}
+
+// WithSecretSnippet marks the finding as having a code snippet that contains a secret.
+// The snippet is neither kept nor logged, nor is its hash or length, which would let
+// readers of the logs guess short secrets. The snippet is logged as [REDACTED].
+func (f *FindingFields) WithSecretSnippet(_ string) *FindingFields {
+	f.Snippet = redactedSnippet
+	f.snippetSet = true
+	f.snippetSecret = true
+	return f
+}
+
+// WithFinding adds security finding-related fields to the log entry
+func WithFinding(finding FindingFields) Field {
+	fields := map[string]any{
+		"rule_id":  finding.RuleID,
+		"severity": string(finding.Severity),
+		"tool":     finding.Tool,
+	}
+
+	if finding.cweSet {
+		fields["cwe"] = finding.CWE
+	}
+	if finding.cveSet {
+		fields["cve"] = finding.CVE
+	}
+	if finding.confidenceSet {
+		fields["confidence"] = finding.Confidence
+	}
+	if finding.locationSet {
+		fields["file_path"] = finding.FilePath
+		fields["start_line"] = finding.StartLine
+		fields["end_line"] = finding.EndLine
+	}
+	if finding.fingerprintSet {
+		fields["fingerprint"] = finding.Fingerprint
+	}
+	if finding.snippetSet {
+		if finding.snippetSecret {
+			fields["snippet"] = redactedSnippet
+		} else {
+			fields["snippet"] = truncateSnippet(finding.Snippet)
+		}
+	}
+
+	return libraryfield.MarkObject(Any("finding", fields))
+}
+
+// truncateSnippet limits the snippet to MaxFindingSnippetSize bytes without splitting a rune
+func truncateSnippet(snippet string) string {
+	if len(snippet) <= MaxFindingSnippetSize {
+		return snippet
+	}
+	return strings.ToValidUTF8(snippet[:MaxFindingSnippetSize], "") + "...[truncated]"
+}
+
+// RecordFinding increments the finding counter using the meter from the context
+func RecordFinding(ctx context.Context, finding FindingFields) {
+	if ctx == nil {
+		return
+	}
+
+	m := meter.FromContext(ctx)
+	if m == nil {
+		return
+	}
+
+	counter, err := m.Int64Counter(
+		"security.findings",
+		metric.WithDescription("Number of security findings reported"),
+		metric.WithUnit("{finding}"),
+	)
+	if err != nil {
+		return
+	}
+
+	counter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("severity", strings.ToLower(string(finding.Severity))),
+		attribute.String("tool", finding.Tool),
+	))
+}
@@ -3,6 +3,7 @@ package logger
 import (
 	"context"
 
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
 	"github.com/nullify-platform/logger/pkg/logger/meter"
 	"github.com/nullify-platform/logger/pkg/logger/tracer"
 	"go.opentelemetry.io/otel/trace"
@@ -20,11 +21,11 @@ func L(ctx context.Context) Logger {
 	if l, ok := ctx.Value(loggerCtxKey{}).(Logger); ok {
 		spanContext := trace.SpanFromContext(ctx).SpanContext()
 		if traceID := spanContext.TraceID(); traceID.IsValid() {
-			fields = append(fields, zap.String("trace-id", traceID.String()))
+			fields = append(fields, libraryfield.Mark(zap.String("trace-id", traceID.String())))
 		}
 
 		if spanID := spanContext.SpanID(); spanID.IsValid() {
-			fields = append(fields, zap.String("span-id", spanID.String()))
+			fields = append(fields, libraryfield.Mark(zap.String("span-id", spanID.String())))
 		}
 
 		l := l.NewChild(fields...)
@@ -44,3 +45,36 @@ func CopyFromContext(fromCtx context.Context, toCtx context.Context) context.Con
 	toCtx = meter.CopyFromContext(fromCtx, toCtx)
 	return toCtx
 }
+
+// Detach returns a context that is never canceled and has no deadline but keeps the logger,
+// tracer, meter, active span and metadata of ctx, for work that outlives the request of ctx.
+// The NullifyContext, if any, is copied so the detached work cannot change that of ctx.
+func Detach(ctx context.Context) context.Context {
+	detached := context.WithoutCancel(ctx)
+	if nullifyContext, ok := ctx.Value(nullifyContextKey).(*NullifyContext); ok {
+		detached = context.WithValue(detached, nullifyContextKey, nullifyContext.Snapshot())
+	}
+	return detached
+}
+
+// DetachWithLinkedSpan is like Detach but also starts a new root span linked to the span of ctx,
+// so fire-and-forget work gets its own trace instead of extending one that has already ended.
+func DetachWithLinkedSpan(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	detached := Detach(ctx)
+
+	t := tracer.FromContext(detached)
+	if t == nil {
+		t = trace.SpanFromContext(detached).TracerProvider().Tracer("")
+	}
+
+	opts = append(opts, trace.WithNewRoot(), trace.WithLinks(trace.LinkFromContext(ctx)))
+	detached, span := t.Start(detached, spanName, opts...)
+
+	if nullifyContext, ok := detached.Value(nullifyContextKey).(*NullifyContext); ok {
+		nullifyContext.mu.Lock()
+		nullifyContext.Span = span
+		nullifyContext.mu.Unlock()
+	}
+
+	return detached, span
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/nullify-platform/logger/pkg/logger/meter"
+	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDetach(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	ctx, nullifyContext := GetNullifyContext(ctx)
+	ctx = WithRepositoryMetadata(ctx, Repository{Name: "logger"})
+	ctx, cancel := context.WithCancel(ctx)
+
+	detached := Detach(ctx)
+	cancel()
+
+	assert.Error(t, ctx.Err())
+	assert.NoError(t, detached.Err())
+	_, hasDeadline := detached.Deadline()
+	assert.False(t, hasDeadline)
+
+	assert.NotNil(t, L(detached))
+	assert.NotNil(t, tracer.FromContext(detached))
+	assert.NotNil(t, meter.FromContext(detached))
+	assert.Equal(t, trace.SpanFromContext(ctx), trace.SpanFromContext(detached))
+	assert.Equal(t, "logger", LogConfigFromContext(detached).Repository.Name)
+
+	_, detachedNullifyContext := GetNullifyContext(detached)
+	assert.NotSame(t, nullifyContext, detachedNullifyContext)
+	detachedNullifyContext.Update(func(cfg *LogConfig) { cfg.Tool.Name = "semgrep" })
+	assert.Empty(t, nullifyContext.Snapshot().LogConfig.Tool.Name)
+}
+
+func TestDetachWithLinkedSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	ctx := tracer.NewContext(t.Context(), sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), "test-tracer")
+	ctx, nullifyContext := GetNullifyContext(ctx)
+	parent := trace.SpanFromContext(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	detached, span := DetachWithLinkedSpan(ctx, "background")
+	cancel()
+	span.End()
+
+	assert.NoError(t, detached.Err())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "background", spans[0].Name())
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.NotEqual(t, parent.SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+	require.Len(t, spans[0].Links(), 1)
+	assert.Equal(t, parent.SpanContext(), spans[0].Links()[0].SpanContext)
+
+	_, detachedNullifyContext := GetNullifyContext(detached)
+	assert.Equal(t, span, detachedNullifyContext.CurrentSpan())
+	assert.Equal(t, parent, nullifyContext.CurrentSpan())
+}
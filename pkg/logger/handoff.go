@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	// ContextEnvVar is the environment variable holding a marshaled ContextCarrier for subprocesses
+	ContextEnvVar = "NULLIFY_CONTEXT"
+
+	// ContextCarrierVersion is the version of the ContextCarrier format written by MarshalContext
+	ContextCarrierVersion = 1
+
+	// traceparentEnvVar and tracestateEnvVar follow the OpenTelemetry environment variable
+	// carrier convention, so instrumented children that do not read NULLIFY_CONTEXT still join the trace
+	traceparentEnvVar = "TRACEPARENT"
+	tracestateEnvVar  = "TRACESTATE"
+)
+
+// ContextCarrier is the language-neutral JSON form of the trace context, baggage and
+// LogConfig metadata of a context, handed to subprocesses in NULLIFY_CONTEXT:
+//
+//	{
+//	  "v": 1,
+//	  "traceparent": "00-<trace id>-<span id>-01",
+//	  "tracestate": "vendor=value",
+//	  "baggage": "key=value",
+//	  "logConfig": {"repositoryName": "api", "organizationId": "org-1"}
+//	}
+//
+// traceparent, tracestate and baggage use the W3C header formats. logConfig uses the
+// LogConfig json keys and omits empty values. Readers should ignore unknown keys.
+type ContextCarrier struct {
+	Version     int               `json:"v"`
+	TraceParent string            `json:"traceparent,omitempty"`
+	TraceState  string            `json:"tracestate,omitempty"`
+	Baggage     string            `json:"baggage,omitempty"`
+	LogConfig   map[string]string `json:"logConfig,omitempty"`
+}
+
+// handoffPropagator writes the W3C headers regardless of the globally registered propagator
+var handoffPropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// NewContextCarrier captures the current span, baggage and LogConfig metadata of ctx
+func NewContextCarrier(ctx context.Context) ContextCarrier {
+	headers := propagation.MapCarrier{}
+	handoffPropagator.Inject(ctx, headers)
+
+	carrier := ContextCarrier{
+		Version:     ContextCarrierVersion,
+		TraceParent: headers.Get("traceparent"),
+		TraceState:  headers.Get("tracestate"),
+		Baggage:     headers.Get("baggage"),
+	}
+
+	cfg := reflect.ValueOf(LogConfigFromContext(ctx))
+	for _, f := range logConfigPlan {
+		if value := cfg.FieldByIndex(f.index).String(); value != "" {
+			if carrier.LogConfig == nil {
+				carrier.LogConfig = map[string]string{}
+			}
+			carrier.LogConfig[f.key] = value
+		}
+	}
+
+	return carrier
+}
+
+// Restore returns a copy of ctx with the remote span, baggage and LogConfig metadata of the carrier.
+// Spans started from the returned context are children of the span that created the carrier.
+func (c ContextCarrier) Restore(ctx context.Context) context.Context {
+	ctx = handoffPropagator.Extract(ctx, propagation.MapCarrier{
+		"traceparent": c.TraceParent,
+		"tracestate":  c.TraceState,
+		"baggage":     c.Baggage,
+	})
+
+	var cfg LogConfig
+	v := reflect.ValueOf(&cfg).Elem()
+	for key, value := range c.LogConfig {
+		if f, ok := logConfigPlan.field(key); ok {
+			v.FieldByIndex(f.index).SetString(value)
+		}
+	}
+	if cfg != (LogConfig{}) {
+		ctx = WithLogConfig(ctx, cfg)
+	}
+
+	return ctx
+}
+
+// MarshalContext encodes the trace context, baggage and LogConfig metadata of ctx as a ContextCarrier JSON blob
+func MarshalContext(ctx context.Context) ([]byte, error) {
+	return json.Marshal(NewContextCarrier(ctx))
+}
+
+// UnmarshalContext restores a blob written by MarshalContext into ctx
+func UnmarshalContext(ctx context.Context, data []byte) (context.Context, error) {
+	var carrier ContextCarrier
+	if err := json.Unmarshal(data, &carrier); err != nil {
+		return ctx, fmt.Errorf("failed to decode context carrier: %w", err)
+	}
+
+	if carrier.Version > ContextCarrierVersion {
+		return ctx, fmt.Errorf("unsupported context carrier version %d", carrier.Version)
+	}
+
+	return carrier.Restore(ctx), nil
+}
+
+// ContextEnv returns the environment entries handing the context of ctx to a subprocess:
+// NULLIFY_CONTEXT and, when there is a valid span, TRACEPARENT and TRACESTATE
+func ContextEnv(ctx context.Context) ([]string, error) {
+	carrier := NewContextCarrier(ctx)
+
+	data, err := json.Marshal(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	env := []string{ContextEnvVar + "=" + string(data)}
+	if carrier.TraceParent != "" {
+		env = append(env, traceparentEnvVar+"="+carrier.TraceParent)
+	}
+	if carrier.TraceState != "" {
+		env = append(env, tracestateEnvVar+"="+carrier.TraceState)
+	}
+	return env, nil
+}
+
+// SetCommandContext adds the ContextEnv entries of ctx to the environment of cmd.
+// When cmd.Env is nil it starts from the environment of the current process, as exec.Cmd does.
+func SetCommandContext(ctx context.Context, cmd *exec.Cmd) error {
+	env, err := ContextEnv(ctx)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, env...)
+	return nil
+}
+
+// ContextFromEnv restores the context handed over by a parent process in NULLIFY_CONTEXT.
+// ctx is returned unchanged if the variable is not set.
+func ContextFromEnv(ctx context.Context) (context.Context, error) {
+	data := os.Getenv(ContextEnvVar)
+	if data == "" {
+		return ctx, nil
+	}
+	return UnmarshalContext(ctx, []byte(data))
+}
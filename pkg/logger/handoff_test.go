@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMarshalContextRoundTrip(t *testing.T) {
+	member, err := baggage.NewMember("tenant", "acme")
+	require.NoError(t, err)
+	bag, err := baggage.New(member)
+	require.NoError(t, err)
+
+	ctx := baggage.ContextWithBaggage(t.Context(), bag)
+	ctx = WithRepositoryMetadata(ctx, Repository{Name: "logger", OrganizationID: "org-1"})
+	ctx = WithToolMetadata(ctx, Tool{Name: "semgrep"})
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(ctx, "parent")
+	defer span.End()
+
+	data, err := MarshalContext(ctx)
+	require.NoError(t, err)
+
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, float64(1), raw["v"])
+	assert.Equal(t, map[string]any{"repositoryName": "logger", "organizationId": "org-1", "toolName": "semgrep"}, raw["logConfig"])
+
+	restored, err := UnmarshalContext(t.Context(), data)
+	require.NoError(t, err)
+
+	spanContext := trace.SpanContextFromContext(restored)
+	assert.True(t, spanContext.IsRemote())
+	assert.Equal(t, span.SpanContext().TraceID(), spanContext.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), spanContext.SpanID())
+	assert.Equal(t, "acme", baggage.FromContext(restored).Member("tenant").Value())
+	assert.Equal(t, LogConfig{
+		Repository: Repository{Name: "logger", OrganizationID: "org-1"},
+		Tool:       Tool{Name: "semgrep"},
+	}, LogConfigFromContext(restored))
+}
+
+func TestUnmarshalContextErrors(t *testing.T) {
+	_, err := UnmarshalContext(t.Context(), []byte("not json"))
+	assert.Error(t, err)
+
+	_, err = UnmarshalContext(t.Context(), []byte(`{"v":2}`))
+	assert.Error(t, err)
+
+	restored, err := UnmarshalContext(t.Context(), []byte(`{"v":1,"logConfig":{"repositoryName":"logger","unknown":"x"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "logger", LogConfigFromContext(restored).Repository.Name)
+	assert.False(t, trace.SpanContextFromContext(restored).IsValid())
+}
+
+func TestSetCommandContext(t *testing.T) {
+	t.Setenv("EXISTING_VAR", "kept")
+
+	ctx := WithServiceMetadata(t.Context(), Service{Name: "orchestrator"})
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(ctx, "parent")
+	defer span.End()
+
+	cmd := exec.Command("python3", "-m", "tool")
+	require.NoError(t, SetCommandContext(ctx, cmd))
+
+	env := map[string]string{}
+	for _, entry := range cmd.Env {
+		key, value, _ := strings.Cut(entry, "=")
+		env[key] = value
+	}
+
+	assert.Equal(t, "kept", env["EXISTING_VAR"])
+	assert.Contains(t, env["TRACEPARENT"], span.SpanContext().TraceID().String())
+	require.Contains(t, env, ContextEnvVar)
+
+	t.Setenv(ContextEnvVar, env[ContextEnvVar])
+	restored, err := ContextFromEnv(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "orchestrator", LogConfigFromContext(restored).Service.Name)
+	assert.Equal(t, span.SpanContext().TraceID(), trace.SpanContextFromContext(restored).TraceID())
+}
+
+func TestContextFromEnvUnset(t *testing.T) {
+	t.Setenv(ContextEnvVar, "")
+
+	restored, err := ContextFromEnv(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, t.Context(), restored)
+}
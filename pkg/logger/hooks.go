@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// writeHook is run by hookCore when the log entry carrying it is written
+type writeHook func()
+
+// onWrite returns a field that is not encoded but runs hook once the entry it is logged with is written,
+// so side effects such as metrics happen once per emitted entry, and not for disabled levels
+func onWrite(hook func()) Field {
+	return zapcore.Field{Type: zapcore.SkipType, Interface: writeHook(hook)}
+}
+
+// hookCore runs the write hooks of the fields of every entry it writes, once per entry
+// whatever the number of outputs and chunks it is encoded to
+type hookCore struct {
+	zapcore.Core
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{Core: c.Core.With(fields)}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	for _, f := range fields {
+		if hook, ok := f.Interface.(writeHook); ok && f.Type == zapcore.SkipType {
+			hook()
+		}
+	}
+	return c.Core.Write(ent, fields)
+}
@@ -3,21 +3,45 @@ package logger
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
+	"github.com/nullify-platform/logger/pkg/logger/meter"
 	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // NewLoggingTransport creates a new http.RoundTripper that logs requests and responses
 // with the global logge
-func NewLoggingTransport(baseCtx context.Context, baseTransport http.RoundTripper, service string) http.RoundTripper {
-	return &LoggingTransport{
+func NewLoggingTransport(baseCtx context.Context, baseTransport http.RoundTripper, service string, opts ...TransportOption) http.RoundTripper {
+	t := &LoggingTransport{
 		baseTransport: baseTransport,
 		service:       service,
 		ctx:           baseCtx,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// TransportOption configures a LoggingTransport created with NewLoggingTransport
+type TransportOption func(*LoggingTransport)
+
+// WithBodyCapture logs the request and response bodies of the requests selected by capture.
+// The request summary is then logged, and the span ended, once the response body is read
+// to the end or closed.
+func WithBodyCapture(capture BodyCapture) TransportOption {
+	return func(t *LoggingTransport) {
+		t.bodyCapture = &capture
+	}
 }
 
 // LoggingTransport is an http.RoundTripper that logs HTTP requests and responses
@@ -26,38 +50,218 @@ type LoggingTransport struct {
 	logger        Logger
 	service       string
 	ctx           context.Context
+	bodyCapture   *BodyCapture
+	metricHosts   hostGuard
 }
 
-// RoundTrip executes the HTTP request and logs the request and response summary
+// RoundTrip executes the HTTP request and logs the request and response summary.
+// The client span is started from the request context, falling back to the logger, tracer,
+// meter and span of the base context for requests made without them, and the trace context
+// is injected into the outgoing request headers. HTTP client metrics are recorded with the
+// meter of the request context.
 func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	ctx, span := tracer.FromContext(t.ctx).Start(t.ctx, req.URL.EscapedPath())
-	defer L(ctx).Sync()
-	defer span.End()
+	ctx := t.requestContext(req)
+
+	// requests are still logged without a tracer, with the span of the context if any
+	spanTracer := tracer.FromContext(ctx)
+	if spanTracer == nil {
+		spanTracer = trace.SpanFromContext(ctx).TracerProvider().Tracer("")
+	}
+
+	ctx, span := spanTracer.Start(ctx, clientSpanName(req),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(httpClientAttributes(req)...),
+	)
+
+	// the request must not be modified by a RoundTripper, so the headers are injected into a clone
+	req = req.Clone(ctx)
+	tracer.InjectTracingIntoHTTPHeaders(ctx, req.Header)
 
+	capture := t.bodyCapture.Captures(req)
+	var requestBody *BodyRecorder
+	if capture && req.Body != nil && req.Body != http.NoBody {
+		requestBody = t.bodyCapture.NewRecorder(req.Header.Get("Content-Type"))
+		req.Body = &recordingBody{ReadCloser: req.Body, recorder: requestBody}
+	}
+
+	attempt := nextAttempt(ctx)
+	if attempt > 1 {
+		span.SetAttributes(attribute.Int("http.request.resend_count", attempt-1))
+	}
+
+	var deadlineRemaining time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		deadlineRemaining = time.Until(deadline)
+	}
+
+	metrics := t.startRequestMetrics(ctx, req)
 	start := time.Now()
 
 	res, err := t.baseTransport.RoundTrip(req)
-	if err != nil {
-		return res, err
+	duration := time.Since(start)
+	metrics.end(duration, req, res, err)
+
+	summary := createRequestSummary(t.service, duration, req, res)
+	summary.Attempt = attempt
+	summary.DeadlineRemaining = deadlineRemaining
+
+	requestLogger := t.logger
+	if requestLogger == nil {
+		requestLogger = L(ctx)
+	}
+	if requestLogger == nil {
+		// requests made without a logger in their context or the base context are logged with the global logger
+		requestLogger = &logger{underlyingLogger: zap.L()}
 	}
 
-	summary := createRequestSummary(t.service, time.Since(start), req, res)
-	logger := t.logger
-	if logger == nil {
-		logger = L(ctx)
+	// finish logs the request and ends the span, once the response body is captured if capturing
+	finish := func(responseBody *BodyRecorder) {
+		// flushed without the cancellation of the request, which may have timed out
+		if l := L(context.WithoutCancel(ctx)); l != nil {
+			defer l.Sync()
+		}
+		defer span.End()
+
+		fields := []Field{requestSummaryField(summary)}
+		if requestBody != nil {
+			fields = append(fields, t.bodyCapture.Attach(span, "requestBody", requestBody.Captured())...)
+		}
+		if responseBody != nil {
+			fields = append(fields, t.bodyCapture.Attach(span, "responseBody", responseBody.Captured())...)
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			// the error is recorded on the span above, and transport errors carry no useful stack trace
+			fields = append(WithErrorInfo(ErrorFields{Type: ClassifyError(err), Message: err.Error()}), fields...)
+			logErrorWithoutSpan(requestLogger, "request failed", fields...)
+			return
+		}
+
+		span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+		if res.StatusCode >= 400 {
+			span.SetStatus(codes.Error, res.Status)
+		}
+
+		if res.StatusCode >= 500 {
+			requestLogger.Warn("request summary", fields...)
+		} else if res.StatusCode >= 400 {
+			requestLogger.Info("request summary", fields...)
+		} else {
+			requestLogger.Debug("request summary", fields...)
+		}
 	}
 
-	if res.StatusCode >= 500 {
-		logger.Warn("request summary", summary)
-	} else if res.StatusCode >= 400 {
-		logger.Info("request summary", summary)
-	} else {
-		logger.Debug("request summary", summary)
+	// the response body of protocol switches is a connection, not a body
+	if err == nil && capture && res.Body != nil && res.Body != http.NoBody && res.StatusCode != http.StatusSwitchingProtocols {
+		responseBody := t.bodyCapture.NewRecorder(res.Header.Get("Content-Type"))
+		res.Body = &recordingBody{ReadCloser: res.Body, recorder: responseBody, done: func() { finish(responseBody) }}
+		return res, nil
 	}
 
+	finish(nil)
 	return res, err
 }
 
+// clientSpanName returns the span name of an outgoing request, its method as recommended by the
+// HTTP semantic conventions, as the URL path is not a low-cardinality route
+func clientSpanName(req *http.Request) string {
+	if !metricMethods[req.Method] {
+		return "HTTP"
+	}
+	return req.Method
+}
+
+// retryAttemptsCtxKey holds the attempt counter added by WithRetryAttempts
+type retryAttemptsCtxKey struct{}
+
+// WithRetryAttempts returns a copy of ctx counting the attempts of the request made with it
+// through LoggingTransport, for clients that retry a request with the same context. Use a new
+// context for every logical request. The attempt number is logged in the request summary and
+// retries set http.request.resend_count on their span.
+func WithRetryAttempts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryAttemptsCtxKey{}, new(atomic.Int64))
+}
+
+// nextAttempt counts an attempt of the request of ctx and returns its number, or 0 if attempts are not counted
+func nextAttempt(ctx context.Context) int {
+	attempts, ok := ctx.Value(retryAttemptsCtxKey{}).(*atomic.Int64)
+	if !ok {
+		return 0
+	}
+	return int(attempts.Add(1))
+}
+
+// requestContext returns the context of req, with each of the logger, tracer and meter of the
+// base context it has none of, and the span of the base context if it has no span, e.g. for
+// requests made with http.NewRequest
+func (t *LoggingTransport) requestContext(req *http.Request) context.Context {
+	ctx := req.Context()
+	if t.ctx == nil {
+		return ctx
+	}
+
+	if l := t.ctx.Value(loggerCtxKey{}); l != nil && ctx.Value(loggerCtxKey{}) == nil {
+		ctx = context.WithValue(ctx, loggerCtxKey{}, l)
+	}
+	if tracer.FromContext(t.ctx) != nil && tracer.FromContext(ctx) == nil {
+		ctx = tracer.CopyFromContext(t.ctx, ctx)
+	}
+	if meter.FromContext(t.ctx) != nil && meter.FromContext(ctx) == nil {
+		ctx = meter.CopyFromContext(t.ctx, ctx)
+	}
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = trace.ContextWithSpan(ctx, trace.SpanFromContext(t.ctx))
+	}
+	return ctx
+}
+
+// sensitiveQueryParameters are the query parameters redacted from url.full, as recommended by the HTTP semantic conventions
+var sensitiveQueryParameters = []string{"AWSAccessKeyId", "Signature", "sig", "X-Goog-Signature", "X-Amz-Signature", "X-Amz-Credential", "X-Amz-Security-Token"}
+
+// httpClientAttributes returns the HTTP semantic convention attributes of an outgoing request
+func httpClientAttributes(req *http.Request) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", sanitizeURL(req.URL)),
+		attribute.String("server.address", req.URL.Hostname()),
+	}
+
+	if port := req.URL.Port(); port != "" {
+		if number, err := strconv.Atoi(port); err == nil {
+			attributes = append(attributes, attribute.Int("server.port", number))
+		}
+	}
+
+	return attributes
+}
+
+// sanitizeURL returns u with its credentials and sensitive query parameter values redacted
+func sanitizeURL(u *url.URL) string {
+	sanitized := *u
+	if sanitized.User != nil {
+		sanitized.User = url.UserPassword("REDACTED", "REDACTED")
+	}
+
+	if sanitized.RawQuery != "" {
+		query := sanitized.Query()
+		redacted := false
+		for _, key := range sensitiveQueryParameters {
+			if query.Has(key) {
+				query.Set(key, "REDACTED")
+				redacted = true
+			}
+		}
+		if redacted {
+			sanitized.RawQuery = query.Encode()
+		}
+	}
+
+	return sanitized.String()
+}
+
 // HTTPRequestSummary is a JSON struct definition for the summary of an HTTP request
 type HTTPRequestSummary struct {
 	Service         string        `json:"service"`
@@ -68,12 +272,16 @@ type HTTPRequestSummary struct {
 	RequestHeaders  []string      `json:"requestHeaders"`
 	ResponseHeaders []string      `json:"responseHeaders"`
 	Duration        time.Duration `json:"duration"`
+	// Attempt is the number of the attempt of a request retried with a context from WithRetryAttempts
+	Attempt int `json:"attempt,omitempty"`
+	// DeadlineRemaining is the time left before the deadline of the request context when the request was sent
+	DeadlineRemaining time.Duration `json:"deadlineRemaining,omitempty"`
 }
 
 // HTTPRequest logs a summary of an HTTP request
 // service is the name of the service the request is being made to
 func (l *logger) HTTPRequest(service string, duration time.Duration, req *http.Request, res *http.Response) {
-	summary := createRequestSummary(service, duration, req, res)
+	summary := requestSummaryField(createRequestSummary(service, duration, req, res))
 
 	if res.StatusCode >= 500 {
 		l.Error("request summary", summary)
@@ -87,7 +295,7 @@ func (l *logger) HTTPRequest(service string, duration time.Duration, req *http.R
 // HTTPRequest logs a summary of an HTTP request
 // service is the name of the service the request is being made to
 func HTTPRequest(service string, duration time.Duration, req *http.Request, res *http.Response) {
-	summary := createRequestSummary(service, duration, req, res)
+	summary := requestSummaryField(createRequestSummary(service, duration, req, res))
 
 	if res.StatusCode >= 500 {
 		zap.L().Warn("request summary", summary)
@@ -98,7 +306,12 @@ func HTTPRequest(service string, duration time.Duration, req *http.Request, res
 	}
 }
 
-func createRequestSummary(service string, duration time.Duration, req *http.Request, res *http.Response) zap.Field {
+// requestSummaryField returns the requestSummary field of an HTTP request summary
+func requestSummaryField(summary *HTTPRequestSummary) Field {
+	return libraryfield.Mark(Any("requestSummary", summary))
+}
+
+func createRequestSummary(service string, duration time.Duration, req *http.Request, res *http.Response) *HTTPRequestSummary {
 	reqHeaders := []string{}
 	for header, values := range req.Header {
 		if strings.ToLower(header) == "authorization" {
@@ -111,21 +324,26 @@ func createRequestSummary(service string, duration time.Duration, req *http.Requ
 		}
 	}
 
+	// res is nil when the request failed
 	resHeaders := []string{}
-	for header, values := range res.Header {
-		for _, value := range values {
-			resHeaders = append(resHeaders, header+": "+value)
+	statusCode := 0
+	if res != nil {
+		statusCode = res.StatusCode
+		for header, values := range res.Header {
+			for _, value := range values {
+				resHeaders = append(resHeaders, header+": "+value)
+			}
 		}
 	}
 
-	return Any("requestSummary", &HTTPRequestSummary{
+	return &HTTPRequestSummary{
 		Service:         service,
 		Host:            req.Host,
 		Method:          req.Method,
-		URL:             req.URL.String(),
-		StatusCode:      res.StatusCode,
+		URL:             sanitizeURL(req.URL),
+		StatusCode:      statusCode,
 		RequestHeaders:  reqHeaders,
 		ResponseHeaders: resHeaders,
 		Duration:        duration,
-	})
+	}
 }
@@ -0,0 +1,278 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultBodyCaptureBytes is the default maximum number of bytes captured per body
+	DefaultBodyCaptureBytes = 8192
+
+	// redactedValue replaces the values of redacted JSON keys and form fields
+	redactedValue = "[REDACTED]"
+)
+
+// defaultRedactKeys are redacted from captured bodies in every JSON key and form field containing them,
+// e.g. private_token, x-api-key or client_secret_value
+var defaultRedactKeys = []string{
+	"token", "secret", "password", "passwd", "apiKey", "authorization", "privateKey", "credential", "jwt",
+}
+
+// BodyCapture enables the capture of HTTP request and response bodies by LoggingTransport,
+// see WithBodyCapture, and by middleware.LoggingMiddlewareWithBodyCapture.
+// Bodies are recorded as they are read or written, so streaming is not affected.
+type BodyCapture struct {
+	// MaxBytes is the maximum number of bytes captured per body, DefaultBodyCaptureBytes if 0
+	MaxBytes int
+	// RedactKeys are redacted in addition to the defaults, e.g. password or token, in every JSON key and
+	// form field containing one of them. Keys match regardless of case, underscores and dashes.
+	RedactKeys []string
+	// Match selects the requests whose bodies are captured, e.g. MatchHosts or MatchPathPrefixes.
+	// Every request is captured if Match is nil.
+	Match func(req *http.Request) bool
+	// SpanEvents attaches the bodies as http.request.body and http.response.body span events
+	// instead of requestBody and responseBody log fields
+	SpanEvents bool
+}
+
+// CapturedBody is an HTTP body captured by BodyCapture
+type CapturedBody struct {
+	ContentType string `json:"contentType,omitempty"`
+	// Size is the number of bytes read or written
+	Size int64 `json:"size"`
+	// Content is the redacted start of JSON and form bodies. It is empty for other content types,
+	// such as text or XML, whose secrets cannot be redacted, and only their size is logged.
+	Content string `json:"content,omitempty"`
+	// Truncated reports whether the body was larger than the capture limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// MatchHosts returns a BodyCapture.Match selecting the requests to the given hosts, without port
+func MatchHosts(hosts ...string) func(req *http.Request) bool {
+	return func(req *http.Request) bool {
+		host := req.URL.Hostname()
+		if host == "" {
+			host, _, _ = strings.Cut(req.Host, ":")
+		}
+		return slices.ContainsFunc(hosts, func(h string) bool { return strings.EqualFold(h, host) })
+	}
+}
+
+// MatchPathPrefixes returns a BodyCapture.Match selecting the requests whose path starts with one of prefixes
+func MatchPathPrefixes(prefixes ...string) func(req *http.Request) bool {
+	return func(req *http.Request) bool {
+		return slices.ContainsFunc(prefixes, func(prefix string) bool { return strings.HasPrefix(req.URL.Path, prefix) })
+	}
+}
+
+// Captures reports whether the bodies of req are captured. A nil BodyCapture captures nothing.
+func (c *BodyCapture) Captures(req *http.Request) bool {
+	return c != nil && (c.Match == nil || c.Match(req))
+}
+
+// NewRecorder returns a BodyRecorder capturing a body of the given content type
+func (c *BodyCapture) NewRecorder(contentType string) *BodyRecorder {
+	limit := c.MaxBytes
+	if limit <= 0 {
+		limit = DefaultBodyCaptureBytes
+	}
+
+	var keys redactKeys
+	for _, key := range append(slices.Clone(defaultRedactKeys), c.RedactKeys...) {
+		if key = normalizeRedactKey(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return &BodyRecorder{contentType: contentType, limit: limit, redactKeys: keys}
+}
+
+// Attach returns body as a log field named key, requestBody or responseBody, or adds it as
+// a span event to span if SpanEvents is set. Nil bodies are skipped.
+func (c *BodyCapture) Attach(span trace.Span, key string, body *CapturedBody) []Field {
+	if body == nil {
+		return nil
+	}
+
+	if !c.SpanEvents {
+		return []Field{libraryfield.Mark(Any(key, body))}
+	}
+
+	name := "http.request.body"
+	if key == "responseBody" {
+		name = "http.response.body"
+	}
+	span.AddEvent(name, trace.WithAttributes(
+		attribute.String("http.body.content_type", body.ContentType),
+		attribute.Int64("http.body.size", body.Size),
+		attribute.String("http.body.content", body.Content),
+		attribute.Bool("http.body.truncated", body.Truncated),
+	))
+	return nil
+}
+
+// BodyRecorder records the start of an HTTP body written to it, up to the capture limit.
+// It is safe for concurrent use, as a transport may still be sending a request body
+// when the response arrives.
+type BodyRecorder struct {
+	mu          sync.Mutex
+	contentType string
+	limit       int
+	redactKeys  redactKeys
+	buf         bytes.Buffer
+	size        int64
+}
+
+// Write records p and never fails, so the recorder can be used with io.TeeReader and io.MultiWriter
+func (r *BodyRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.size += int64(len(p))
+	if remaining := r.limit - r.buf.Len(); remaining > 0 {
+		r.buf.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+// Captured returns the redacted body recorded so far
+func (r *BodyRecorder) Captured() *CapturedBody {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body := &CapturedBody{ContentType: r.contentType, Size: r.size, Truncated: r.size > int64(r.buf.Len())}
+	body.Content = redactBody(r.contentType, r.buf.Bytes(), body.Truncated, r.redactKeys)
+	return body
+}
+
+// recordingBody tees the bytes read from a body into a recorder and calls done once,
+// at the end of the body or when it is closed
+type recordingBody struct {
+	io.ReadCloser
+
+	recorder *BodyRecorder
+	done     func()
+	once     sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	_, _ = b.recorder.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+func (b *recordingBody) finish() {
+	if b.done != nil {
+		b.once.Do(b.done)
+	}
+}
+
+// redactKeys are normalized key fragments, see normalizeRedactKey
+type redactKeys []string
+
+// matches reports whether key contains one of the fragments, regardless of case, underscores and dashes
+func (k redactKeys) matches(key string) bool {
+	key = normalizeRedactKey(key)
+	return slices.ContainsFunc(k, func(fragment string) bool { return strings.Contains(key, fragment) })
+}
+
+// jsonStringFieldPattern matches "key": value pairs of JSON that may be truncated
+var jsonStringFieldPattern = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+
+// redactBody returns content with the values of redacted JSON keys or form fields replaced,
+// or an empty string for other content types, which cannot be redacted
+func redactBody(contentType string, content []byte, truncated bool, redactKeys redactKeys) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		values, _ := url.ParseQuery(string(content))
+		for key := range values {
+			if redactKeys.matches(key) {
+				values[key] = []string{redactedValue}
+			}
+		}
+		return values.Encode()
+
+	case strings.HasSuffix(mediaType, "json"):
+		var decoded any
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if !truncated && decoder.Decode(&decoded) == nil {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if enc.Encode(redactJSON(decoded, redactKeys)) == nil {
+				return strings.TrimSuffix(buf.String(), "\n")
+			}
+		}
+
+		// truncated or invalid JSON is redacted key by key, and from the first redacted object or
+		// array to the end, as its nested values cannot be told apart from the rest of the body
+		redacted := string(content)
+		for _, match := range jsonStringFieldPattern.FindAllStringSubmatchIndex(redacted, -1) {
+			key, value := redacted[match[2]:match[3]], redacted[match[6]:match[7]]
+			if redactKeys.matches(key) && (value[0] == '{' || value[0] == '[') {
+				redacted = redacted[:match[6]] + `"` + redactedValue + `"`
+				break
+			}
+		}
+
+		return jsonStringFieldPattern.ReplaceAllStringFunc(redacted, func(field string) string {
+			match := jsonStringFieldPattern.FindStringSubmatch(field)
+			if !redactKeys.matches(match[1]) {
+				return field
+			}
+			return `"` + match[1] + `"` + match[2] + `"` + redactedValue + `"`
+		})
+
+	default:
+		return ""
+	}
+}
+
+// redactJSON replaces the values of redacted keys in a decoded JSON value
+func redactJSON(value any, redactKeys redactKeys) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, nested := range v {
+			if redactKeys.matches(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactJSON(nested, redactKeys)
+			}
+		}
+	case []any:
+		for i, nested := range v {
+			v[i] = redactJSON(nested, redactKeys)
+		}
+	}
+	return value
+}
+
+// normalizeRedactKey lowercases key and removes underscores and dashes, so access_token matches accessToken
+func normalizeRedactKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+}
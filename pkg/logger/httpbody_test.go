@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func captureBody(capture BodyCapture, contentType, body string) *CapturedBody {
+	recorder := capture.NewRecorder(contentType)
+	_, _ = recorder.Write([]byte(body))
+	return recorder.Captured()
+}
+
+func TestBodyCaptureRedactsJSON(t *testing.T) {
+	capture := BodyCapture{RedactKeys: []string{"ssn"}}
+
+	body := captureBody(capture, "application/json; charset=utf-8",
+		`{"user":"ana","password":"hunter2","profile":{"SSN":"123","sessions":[{"access_token":"abc"}]}}`)
+
+	assert.Equal(t, `{"password":"[REDACTED]","profile":{"SSN":"[REDACTED]","sessions":[{"access_token":"[REDACTED]"}]},"user":"ana"}`, body.Content)
+	assert.Equal(t, "application/json; charset=utf-8", body.ContentType)
+	assert.False(t, body.Truncated)
+}
+
+func TestBodyCaptureRedactsTruncatedJSON(t *testing.T) {
+	body := captureBody(BodyCapture{MaxBytes: 54}, "application/json",
+		`{"apiKey": "k-123", "items": [1, 2, 3], "secret": "s3cr3t-value"}`)
+
+	assert.Equal(t, `{"apiKey": "[REDACTED]", "items": [1, 2, 3], "secret": "[REDACTED]"`, body.Content)
+	assert.True(t, body.Truncated)
+	assert.Equal(t, int64(65), body.Size)
+}
+
+func TestBodyCaptureRedactsTruncatedNestedJSON(t *testing.T) {
+	capture := BodyCapture{MaxBytes: 50, RedactKeys: []string{"credentials"}}
+
+	// the value of credentials is cut off, so everything from it is redacted
+	body := captureBody(capture, "application/json",
+		`{"id": 1, "credentials": {"user": "x", "pin": "1234"}, "note": "ok"}`)
+
+	assert.Equal(t, `{"id": 1, "credentials": "[REDACTED]"`, body.Content)
+	assert.True(t, body.Truncated)
+}
+
+func TestBodyCaptureRedactsKeysContainingDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "gitlab webhook",
+			body:     `{"object_kind":"push","user_name":"ana","private_token":"glpat-123","project":{"name":"api","runners_token":"GR134"}}`,
+			expected: `{"object_kind":"push","private_token":"[REDACTED]","project":{"name":"api","runners_token":"[REDACTED]"},"user_name":"ana"}`,
+		},
+		{
+			name:     "jira webhook",
+			body:     `{"webhookEvent":"jira:issue_updated","jwt":"eyJhbGciOi","sharedSecret":"s3cr3t","issue":{"key":"SEC-1"}}`,
+			expected: `{"issue":{"key":"SEC-1"},"jwt":"[REDACTED]","sharedSecret":"[REDACTED]","webhookEvent":"jira:issue_updated"}`,
+		},
+		{
+			name:     "other credential keys",
+			body:     `{"session_token":"a","api_token":"b","x-api-key":"c","client_secret_value":"d","db_credentials":"e","id":"f"}`,
+			expected: `{"api_token":"[REDACTED]","client_secret_value":"[REDACTED]","db_credentials":"[REDACTED]","id":"f","session_token":"[REDACTED]","x-api-key":"[REDACTED]"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, captureBody(BodyCapture{}, "application/json", tt.body).Content)
+		})
+	}
+}
+
+func TestBodyCaptureLogsOnlyTheSizeOfTextAndXML(t *testing.T) {
+	for _, contentType := range []string{"text/plain", "application/xml", "text/xml; charset=utf-8", ""} {
+		body := captureBody(BodyCapture{}, contentType, "<token>glpat-123</token>")
+
+		assert.Empty(t, body.Content, contentType)
+		assert.Equal(t, int64(len("<token>glpat-123</token>")), body.Size, contentType)
+	}
+}
+
+func TestBodyCaptureRedactsForms(t *testing.T) {
+	body := captureBody(BodyCapture{}, "application/x-www-form-urlencoded", "username=ana&client_secret=xyz")
+
+	assert.Equal(t, "client_secret=%5BREDACTED%5D&username=ana", body.Content)
+}
+
+func TestBodyCaptureSkipsBinaryContent(t *testing.T) {
+	body := captureBody(BodyCapture{}, "application/octet-stream", "\x00\x01\x02")
+
+	assert.Empty(t, body.Content)
+	assert.Equal(t, int64(3), body.Size)
+}
+
+func TestBodyCaptureMatch(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "https://api.example.com:8443/v1/users", strings.NewReader("{}"))
+
+	assert.False(t, (*BodyCapture)(nil).Captures(req))
+	assert.True(t, (&BodyCapture{}).Captures(req))
+	assert.True(t, (&BodyCapture{Match: MatchHosts("API.example.com")}).Captures(req))
+	assert.False(t, (&BodyCapture{Match: MatchHosts("example.com")}).Captures(req))
+	assert.True(t, (&BodyCapture{Match: MatchPathPrefixes("/v1/")}).Captures(req))
+	assert.False(t, (&BodyCapture{Match: MatchPathPrefixes("/v2/")}).Captures(req))
+}
@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nullify-platform/logger/pkg/logger/meter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// DefaultMaxMetricHosts is the default number of distinct hosts a LoggingTransport records metrics for
+	DefaultMaxMetricHosts = 50
+
+	// otherMetricValue replaces the hosts beyond the limit and the nonstandard methods in metric attributes,
+	// as the HTTP semantic conventions do for methods
+	otherMetricValue = "_OTHER"
+)
+
+// httpClientDurationBuckets are the bucket boundaries, in seconds, recommended by the HTTP semantic conventions
+var httpClientDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// metricMethods are the HTTP methods recorded as they are in metric attributes
+var metricMethods = map[string]bool{
+	http.MethodConnect: true, http.MethodDelete: true, http.MethodGet: true, http.MethodHead: true, http.MethodOptions: true,
+	http.MethodPatch: true, http.MethodPost: true, http.MethodPut: true, http.MethodTrace: true,
+}
+
+// WithMaxMetricHosts limits the number of distinct server.address values in the metrics of the transport.
+// Requests to further hosts are recorded with server.address _OTHER. The default is DefaultMaxMetricHosts.
+func WithMaxMetricHosts(maxHosts int) TransportOption {
+	return func(t *LoggingTransport) {
+		t.metricHosts.max = maxHosts
+	}
+}
+
+// hostGuard bounds the cardinality of the server.address metric attribute to the first hosts seen
+type hostGuard struct {
+	mu    sync.Mutex
+	max   int
+	hosts map[string]bool
+}
+
+// host returns host if it is one of the first hosts seen, otherwise _OTHER
+func (g *hostGuard) host(host string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.hosts[host] {
+		return host
+	}
+
+	maxHosts := g.max
+	if maxHosts <= 0 {
+		maxHosts = DefaultMaxMetricHosts
+	}
+	if len(g.hosts) >= maxHosts {
+		return otherMetricValue
+	}
+
+	if g.hosts == nil {
+		g.hosts = map[string]bool{}
+	}
+	g.hosts[host] = true
+	return host
+}
+
+// clientRequestMetrics records the HTTP client metrics of a request using the meter from the context
+type clientRequestMetrics struct {
+	ctx    context.Context
+	meter  metric.Meter
+	attrs  []attribute.KeyValue
+	active metric.Int64UpDownCounter
+}
+
+// startRequestMetrics counts req as an active request and returns the metrics to end once the
+// response headers are received, or nil if the context has no meter
+func (t *LoggingTransport) startRequestMetrics(ctx context.Context, req *http.Request) *clientRequestMetrics {
+	m := meter.FromContext(ctx)
+	if m == nil {
+		return nil
+	}
+
+	method := req.Method
+	if !metricMethods[method] {
+		method = otherMetricValue
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("peer.service", t.service),
+		attribute.String("http.request.method", method),
+		attribute.String("server.address", t.metricHosts.host(req.URL.Hostname())),
+	}
+	if port := req.URL.Port(); port != "" {
+		if number, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, attribute.Int("server.port", number))
+		}
+	}
+
+	metrics := &clientRequestMetrics{ctx: ctx, meter: m, attrs: attrs}
+
+	active, err := m.Int64UpDownCounter(
+		"http.client.active_requests",
+		metric.WithDescription("Number of active HTTP client requests"),
+		metric.WithUnit("{request}"),
+	)
+	if err == nil {
+		metrics.active = active
+		active.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+
+	return metrics
+}
+
+// end records the duration and body sizes of the request, with the status class of res or the
+// classified type of err, and stops counting it as active. Body sizes are recorded for bodies of known size.
+func (m *clientRequestMetrics) end(duration time.Duration, req *http.Request, res *http.Response, err error) {
+	if m == nil {
+		return
+	}
+
+	if m.active != nil {
+		m.active.Add(m.ctx, -1, metric.WithAttributes(m.attrs...))
+	}
+
+	attrs := m.attrs
+	if err != nil {
+		attrs = append(attrs, attribute.String("error.type", string(ClassifyError(err))))
+	} else {
+		attrs = append(attrs, attribute.String("http.response.status_class", strconv.Itoa(res.StatusCode/100)+"xx"))
+	}
+	options := metric.WithAttributes(attrs...)
+
+	histogram, histErr := m.meter.Float64Histogram(
+		"http.client.request.duration",
+		metric.WithDescription("Duration of HTTP client requests"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(httpClientDurationBuckets...),
+	)
+	if histErr == nil {
+		histogram.Record(m.ctx, duration.Seconds(), options)
+	}
+
+	// requests without a body, e.g. GET, have a ContentLength of 0 and are not recorded
+	if req.Body != nil && req.Body != http.NoBody && req.ContentLength >= 0 {
+		requestSize, sizeErr := m.meter.Int64Histogram(
+			"http.client.request.body.size",
+			metric.WithDescription("Size of HTTP client request bodies"),
+			metric.WithUnit("By"),
+		)
+		if sizeErr == nil {
+			requestSize.Record(m.ctx, req.ContentLength, options)
+		}
+	}
+
+	if res != nil && res.ContentLength >= 0 {
+		responseSize, sizeErr := m.meter.Int64Histogram(
+			"http.client.response.body.size",
+			metric.WithDescription("Size of HTTP client response bodies"),
+			metric.WithUnit("By"),
+		)
+		if sizeErr == nil {
+			responseSize.Record(m.ctx, res.ContentLength, options)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nullify-platform/logger/pkg/logger/meter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// collectMetrics returns the metrics collected by reader by name
+func collectMetrics(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
+
+func TestLoggingTransportRecordsMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+	defer server.Close()
+
+	reader := sdkmetric.NewManualReader()
+	var buf bytes.Buffer
+	ctx := newTransportTestContext(t, &buf, tracetest.NewSpanRecorder())
+	ctx = meter.NewContext(ctx, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "test-meter")
+	client := &http.Client{Transport: NewLoggingTransport(ctx, http.DefaultTransport, "test-service")}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader("payload"))
+	require.NoError(t, err)
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	metrics := collectMetrics(t, reader)
+
+	duration := metrics["http.client.request.duration"].(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+	attrs := duration.DataPoints[0].Attributes
+	for _, kv := range []attribute.KeyValue{
+		attribute.String("peer.service", "test-service"),
+		attribute.String("http.request.method", http.MethodPost),
+		attribute.String("server.address", "127.0.0.1"),
+		attribute.String("http.response.status_class", "2xx"),
+	} {
+		value, ok := attrs.Value(kv.Key)
+		assert.True(t, ok, kv.Key)
+		assert.Equal(t, kv.Value, value, kv.Key)
+	}
+
+	requestSize := metrics["http.client.request.body.size"].(metricdata.Histogram[int64])
+	assert.Equal(t, int64(len("payload")), requestSize.DataPoints[0].Sum)
+	responseSize := metrics["http.client.response.body.size"].(metricdata.Histogram[int64])
+	assert.Equal(t, int64(len("created")), responseSize.DataPoints[0].Sum)
+
+	active := metrics["http.client.active_requests"].(metricdata.Sum[int64])
+	require.Len(t, active.DataPoints, 1)
+	assert.Equal(t, int64(0), active.DataPoints[0].Value)
+}
+
+func TestLoggingTransportSkipsRequestSizeWithoutBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	reader := sdkmetric.NewManualReader()
+	var buf bytes.Buffer
+	ctx := newTransportTestContext(t, &buf, tracetest.NewSpanRecorder())
+	ctx = meter.NewContext(ctx, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "test-meter")
+	client := &http.Client{Transport: NewLoggingTransport(ctx, http.DefaultTransport, "test-service")}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	metrics := collectMetrics(t, reader)
+	assert.NotContains(t, metrics, "http.client.request.body.size")
+	assert.Contains(t, metrics, "http.client.response.body.size")
+}
+
+func TestLoggingTransportRecordsErrorMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	reader := sdkmetric.NewManualReader()
+	var buf bytes.Buffer
+	ctx := newTransportTestContext(t, &buf, tracetest.NewSpanRecorder())
+	ctx = meter.NewContext(ctx, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "test-meter")
+	client := &http.Client{Transport: NewLoggingTransport(ctx, http.DefaultTransport, "test-service")}
+
+	req, err := http.NewRequestWithContext(ctx, "PURGE", server.URL, nil)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	require.Error(t, err)
+
+	duration := collectMetrics(t, reader)["http.client.request.duration"].(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	attrs := duration.DataPoints[0].Attributes
+	assert.Contains(t, attrs.ToSlice(), attribute.String("error.type", string(ErrorTypeNetwork)))
+	assert.Contains(t, attrs.ToSlice(), attribute.String("http.request.method", otherMetricValue))
+	assert.False(t, attrs.HasValue("http.response.status_class"))
+}
+
+func TestLoggingTransportKeepsRequestMeter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	recorder := tracetest.NewSpanRecorder()
+	baseCtx := newTransportTestContext(t, &buf, recorder)
+	client := &http.Client{Transport: NewLoggingTransport(baseCtx, http.DefaultTransport, "test-service")}
+
+	// the request context has a meter but no logger or tracer, which are taken from the base context
+	reader := sdkmetric.NewManualReader()
+	reqCtx := meter.NewContext(t.Context(), sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "test-meter")
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	duration := collectMetrics(t, reader)["http.client.request.duration"].(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 1)
+	assert.Len(t, recorder.Ended(), 1)
+	assert.Contains(t, buf.String(), "request summary")
+}
+
+func TestHostGuard(t *testing.T) {
+	guard := hostGuard{max: 2}
+
+	assert.Equal(t, "a.example.com", guard.host("a.example.com"))
+	assert.Equal(t, "b.example.com", guard.host("b.example.com"))
+	assert.Equal(t, otherMetricValue, guard.host("c.example.com"))
+	assert.Equal(t, "a.example.com", guard.host("a.example.com"))
+}
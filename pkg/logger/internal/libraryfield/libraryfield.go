@@ -0,0 +1,58 @@
+// Package libraryfield marks the log fields created by the logger packages. Only marked fields
+// have their keys rendered in the field naming convention of the logger writing them,
+// the keys of fields passed in by callers are never renamed.
+package libraryfield
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// The mark is stored in a slot of zapcore.Field that the field type leaves unused, so marked
+// fields encode exactly as unmarked ones: String for every type but StringType, Integer for StringType.
+const (
+	keyMark    = "\x00nullify.library.key"
+	objectMark = "\x00nullify.library.object"
+
+	keyMarkInteger    = 1
+	objectMarkInteger = 2
+)
+
+// Mark returns f marked as created by the logger packages, so its key follows the field naming
+func Mark(f zapcore.Field) zapcore.Field {
+	return mark(f, keyMark, keyMarkInteger)
+}
+
+// MarkObject is Mark for fields whose map[string]any value also has its keys renamed
+func MarkObject(f zapcore.Field) zapcore.Field {
+	return mark(f, objectMark, objectMarkInteger)
+}
+
+func mark(f zapcore.Field, stringMark string, integerMark int64) zapcore.Field {
+	if f.Type == zapcore.StringType {
+		f.Integer = integerMark
+	} else {
+		f.String = stringMark
+	}
+	return f
+}
+
+// Unmark returns f without its mark, whether it was marked, and whether it was marked with MarkObject
+func Unmark(f zapcore.Field) (field zapcore.Field, marked, object bool) {
+	if f.Type == zapcore.StringType {
+		switch f.Integer {
+		case keyMarkInteger, objectMarkInteger:
+			object = f.Integer == objectMarkInteger
+			f.Integer = 0
+			return f, true, object
+		}
+		return f, false, false
+	}
+
+	switch f.String {
+	case keyMark, objectMark:
+		object = f.String == objectMark
+		f.String = ""
+		return f, true, object
+	}
+	return f, false, false
+}
@@ -0,0 +1,206 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
+	"github.com/nullify-platform/logger/pkg/logger/meter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GenAI semantic convention attribute keys
+// https://opentelemetry.io/docs/specs/semconv/gen-ai/
+const (
+	genAIOperationNameKey        = attribute.Key("gen_ai.operation.name")
+	genAIProviderNameKey         = attribute.Key("gen_ai.provider.name")
+	genAIRequestModelKey         = attribute.Key("gen_ai.request.model")
+	genAIResponseIDKey           = attribute.Key("gen_ai.response.id")
+	genAIResponseFinishReasonKey = attribute.Key("gen_ai.response.finish_reasons")
+	genAIUsageInputTokensKey     = attribute.Key("gen_ai.usage.input_tokens")
+	genAIUsageOutputTokensKey    = attribute.Key("gen_ai.usage.output_tokens")
+	genAIUsageCacheReadTokensKey = attribute.Key("gen_ai.usage.cache_read_input_tokens")
+	genAITokenTypeKey            = attribute.Key("gen_ai.token.type")
+
+	// non-standard attributes that have no semantic convention yet
+	genAIUsageTotalTokensKey = attribute.Key("gen_ai.usage.total_tokens")
+	genAIUsageCostKey        = attribute.Key("gen_ai.usage.cost_usd")
+)
+
+// defaultGenAIOperation is the gen_ai.operation.name of LLM calls without an operation
+const defaultGenAIOperation = "chat"
+
+// LLMCallFields represents LLM call-related logging fields
+type LLMCallFields struct {
+	Provider         string
+	Model            string
+	Operation        string // optional, e.g. chat, text_completion or embeddings, defaults to chat
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64         // defaults to PromptTokens + CompletionTokens
+	CacheHits        int64         // optional, number of prompt tokens served from cache
+	Latency          time.Duration // optional
+	CostEstimate     float64       // optional, estimated cost in USD
+	FinishReason     string        // optional
+	RequestID        string        // optional, provider request/response ID
+	cacheHitsSet     bool          // internal tracking
+	latencySet       bool
+	costEstimateSet  bool
+	finishReasonSet  bool
+	requestIDSet     bool
+	operationSet     bool
+}
+
+// Builder methods for LLMCallFields
+
+// WithCacheHits adds the optional number of prompt tokens served from cache
+func (c *LLMCallFields) WithCacheHits(cacheHits int64) *LLMCallFields {
+	c.CacheHits = cacheHits
+	c.cacheHitsSet = true
+	return c
+}
+
+// WithOperation adds the optional GenAI operation of the call, e.g. text_completion or embeddings
+func (c *LLMCallFields) WithOperation(operation string) *LLMCallFields {
+	c.Operation = operation
+	c.operationSet = true
+	return c
+}
+
+// WithLatency adds the optional latency of the call
+func (c *LLMCallFields) WithLatency(latency time.Duration) *LLMCallFields {
+	c.Latency = latency
+	c.latencySet = true
+	return c
+}
+
+// WithCostEstimate adds the optional estimated cost of the call in USD
+func (c *LLMCallFields) WithCostEstimate(costUSD float64) *LLMCallFields {
+	c.CostEstimate = costUSD
+	c.costEstimateSet = true
+	return c
+}
+
+// WithFinishReason adds the optional reason the model stopped generating
+func (c *LLMCallFields) WithFinishReason(reason string) *LLMCallFields {
+	c.FinishReason = reason
+	c.finishReasonSet = true
+	return c
+}
+
+// WithRequestID adds the optional provider request ID
+func (c *LLMCallFields) WithRequestID(requestID string) *LLMCallFields {
+	c.RequestID = requestID
+	c.requestIDSet = true
+	return c
+}
+
+func (c LLMCallFields) operation() string {
+	if c.Operation != "" {
+		return c.Operation
+	}
+	return defaultGenAIOperation
+}
+
+func (c LLMCallFields) totalTokens() int64 {
+	if c.TotalTokens != 0 {
+		return c.TotalTokens
+	}
+	return c.PromptTokens + c.CompletionTokens
+}
+
+// WithLLMCall adds LLM call-related fields to the log entry
+func WithLLMCall(call LLMCallFields) Field {
+	fields := map[string]any{
+		"provider":          call.Provider,
+		"model":             call.Model,
+		"prompt_tokens":     call.PromptTokens,
+		"completion_tokens": call.CompletionTokens,
+		"total_tokens":      call.totalTokens(),
+	}
+
+	if call.cacheHitsSet {
+		fields["cache_hits"] = call.CacheHits
+	}
+	if call.latencySet {
+		fields["latency_ms"] = call.Latency.Milliseconds()
+	}
+	if call.costEstimateSet {
+		fields["cost_estimate_usd"] = call.CostEstimate
+	}
+	if call.finishReasonSet {
+		fields["finish_reason"] = call.FinishReason
+	}
+	if call.requestIDSet {
+		fields["request_id"] = call.RequestID
+	}
+	if call.operationSet {
+		fields["operation"] = call.Operation
+	}
+
+	return libraryfield.MarkObject(Any("llm", fields))
+}
+
+// RecordLLMCall sets GenAI semantic convention attributes on the current span
+// and records token usage and latency histograms using the meter from the context
+func RecordLLMCall(ctx context.Context, call LLMCallFields) {
+	if ctx == nil {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		genAIOperationNameKey.String(call.operation()),
+		genAIProviderNameKey.String(call.Provider),
+		genAIRequestModelKey.String(call.Model),
+		genAIUsageInputTokensKey.Int64(call.PromptTokens),
+		genAIUsageOutputTokensKey.Int64(call.CompletionTokens),
+		genAIUsageTotalTokensKey.Int64(call.totalTokens()),
+	}
+	if call.cacheHitsSet {
+		attrs = append(attrs, genAIUsageCacheReadTokensKey.Int64(call.CacheHits))
+	}
+	if call.costEstimateSet {
+		attrs = append(attrs, genAIUsageCostKey.Float64(call.CostEstimate))
+	}
+	if call.finishReasonSet {
+		attrs = append(attrs, genAIResponseFinishReasonKey.StringSlice([]string{call.FinishReason}))
+	}
+	if call.requestIDSet {
+		attrs = append(attrs, genAIResponseIDKey.String(call.RequestID))
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+
+	m := meter.FromContext(ctx)
+	if m == nil {
+		return
+	}
+
+	metricAttrs := []attribute.KeyValue{
+		genAIOperationNameKey.String(call.operation()),
+		genAIProviderNameKey.String(call.Provider),
+		genAIRequestModelKey.String(call.Model),
+	}
+
+	tokenUsage, err := m.Int64Histogram(
+		"gen_ai.client.token.usage",
+		metric.WithDescription("Number of input and output tokens used"),
+		metric.WithUnit("{token}"),
+	)
+	if err == nil {
+		tokenUsage.Record(ctx, call.PromptTokens, metric.WithAttributes(metricAttrs...), metric.WithAttributes(genAITokenTypeKey.String("input")))
+		tokenUsage.Record(ctx, call.CompletionTokens, metric.WithAttributes(metricAttrs...), metric.WithAttributes(genAITokenTypeKey.String("output")))
+	}
+
+	if call.latencySet {
+		duration, err := m.Float64Histogram(
+			"gen_ai.client.operation.duration",
+			metric.WithDescription("GenAI operation duration"),
+			metric.WithUnit("s"),
+		)
+		if err == nil {
+			duration.Record(ctx, call.Latency.Seconds(), metric.WithAttributes(metricAttrs...))
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/nullify-platform/logger/pkg/logger/meter"
+	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWithLLMCall(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     func() *LLMCallFields
+		expected map[string]any
+	}{
+		{
+			name: "required fields only",
+			call: func() *LLMCallFields {
+				return &LLMCallFields{
+					Provider:         "anthropic",
+					Model:            "claude",
+					PromptTokens:     100,
+					CompletionTokens: 20,
+				}
+			},
+			expected: map[string]any{
+				"provider":          "anthropic",
+				"model":             "claude",
+				"prompt_tokens":     int64(100),
+				"completion_tokens": int64(20),
+				"total_tokens":      int64(120),
+			},
+		},
+		{
+			name: "all fields",
+			call: func() *LLMCallFields {
+				call := &LLMCallFields{
+					Provider:         "openai",
+					Model:            "gpt",
+					PromptTokens:     100,
+					CompletionTokens: 20,
+					TotalTokens:      150,
+				}
+				return call.
+					WithCacheHits(80).
+					WithLatency(1500 * time.Millisecond).
+					WithCostEstimate(0.25).
+					WithFinishReason("stop").
+					WithRequestID("req-123")
+			},
+			expected: map[string]any{
+				"provider":          "openai",
+				"model":             "gpt",
+				"prompt_tokens":     int64(100),
+				"completion_tokens": int64(20),
+				"total_tokens":      int64(150),
+				"cache_hits":        int64(80),
+				"latency_ms":        int64(1500),
+				"cost_estimate_usd": 0.25,
+				"finish_reason":     "stop",
+				"request_id":        "req-123",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := NewLogFields().WithLLMCall(t.Context(), tt.call()).Build()
+			enc := zapcore.NewMapObjectEncoder()
+			for _, f := range fields {
+				f.AddTo(enc)
+			}
+			assert.Equal(t, map[string]any{"llm": tt.expected}, enc.Fields)
+		})
+	}
+}
+
+func TestRecordLLMCall(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+	ctx = tracer.NewContext(ctx, tp, "test-tracer")
+	ctx = meter.NewContext(ctx, mp, "test-meter")
+	ctx, span := tracer.StartNewSpan(ctx, "llm")
+
+	call := &LLMCallFields{
+		Provider:         "anthropic",
+		Model:            "claude",
+		PromptTokens:     100,
+		CompletionTokens: 20,
+	}
+	fields := NewLogFields().WithLLMCall(ctx, call.WithLatency(2*time.Second).WithFinishReason("end_turn")).Build()
+
+	// metrics are recorded once per logged entry, not when the fields are built or not logged
+	L(ctx).Debug("llm call", fields...)
+	L(ctx).Info("llm call", fields...)
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	attrs := attribute.NewSet(spans[0].Attributes()...)
+	value, _ := attrs.Value("gen_ai.provider.name")
+	assert.Equal(t, "anthropic", value.AsString())
+	value, _ = attrs.Value("gen_ai.operation.name")
+	assert.Equal(t, "chat", value.AsString())
+	value, _ = attrs.Value("gen_ai.usage.input_tokens")
+	assert.Equal(t, int64(100), value.AsInt64())
+	value, _ = attrs.Value("gen_ai.usage.output_tokens")
+	assert.Equal(t, int64(20), value.AsInt64())
+	value, _ = attrs.Value("gen_ai.response.finish_reasons")
+	assert.Equal(t, []string{"end_turn"}, value.AsStringSlice())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+
+	tokenUsage, ok := metrics["gen_ai.client.token.usage"].(metricdata.Histogram[int64])
+	require.True(t, ok)
+	require.Len(t, tokenUsage.DataPoints, 2)
+	for _, dp := range tokenUsage.DataPoints {
+		assert.Equal(t, uint64(1), dp.Count)
+		assert.True(t, dp.Attributes.HasValue("gen_ai.operation.name"))
+		tokenType, _ := dp.Attributes.Value("gen_ai.token.type")
+		switch tokenType.AsString() {
+		case "input":
+			assert.Equal(t, int64(100), dp.Sum)
+		case "output":
+			assert.Equal(t, int64(20), dp.Sum)
+		default:
+			t.Errorf("unexpected token type %q", tokenType.AsString())
+		}
+	}
+
+	duration, ok := metrics["gen_ai.client.operation.duration"].(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, duration.DataPoints, 1)
+	assert.InDelta(t, 2.0, duration.DataPoints[0].Sum, 0.001)
+}
+
+func TestWithLLMCallNil(t *testing.T) {
+	assert.Empty(t, NewLogFields().WithLLMCall(t.Context(), nil).Build())
+}
+
+func TestRecordLLMCallWithoutMeter(t *testing.T) {
+	assert.NotPanics(t, func() {
+		RecordLLMCall(t.Context(), LLMCallFields{Provider: "anthropic", Model: "claude"})
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
 	"github.com/nullify-platform/logger/pkg/logger/meter"
 	"github.com/nullify-platform/logger/pkg/logger/tracer"
 	"go.opentelemetry.io/otel/codes"
@@ -40,6 +41,12 @@ type logger struct {
 
 	underlyingLogger *zap.Logger
 	attachedContext  context.Context
+
+	// passContext adds the attached context to the fields of entries, for the oversize
+	// policies of the cores recording values on its span, see contextField
+	passContext bool
+	// spanAttributeNaming is the SPAN_ATTRIBUTE_NAMING the logger was configured with
+	spanAttributeNaming SpanAttributeNaming
 }
 
 type loggerCtxKey struct{}
@@ -57,13 +64,18 @@ func (l *logger) PassContext(ctx context.Context) {
 // NewChild creates a new logger based on the default logger with the given default fields
 func (l *logger) NewChild(fields ...Field) Logger {
 	newLogger := l.underlyingLogger.With(fields...)
-	return &logger{underlyingLogger: newLogger}
+	return l.derive(newLogger)
 }
 
 // WithOptions adds a new field to the default logger
 func (l *logger) WithOptions(opts ...Option) Logger {
 	newLogger := l.underlyingLogger.WithOptions(opts...)
-	return &logger{underlyingLogger: newLogger}
+	return l.derive(newLogger)
+}
+
+// derive returns a logger writing to underlyingLogger with the configuration of l
+func (l *logger) derive(underlyingLogger *zap.Logger) *logger {
+	return &logger{underlyingLogger: underlyingLogger, passContext: l.passContext, spanAttributeNaming: l.spanAttributeNaming}
 }
 
 // AddFields adds new fields to the default logger
@@ -86,61 +98,71 @@ func (l *logger) Sync() {
 	_ = l.underlyingLogger.Sync()
 }
 
+// prepare adds the context metadata to fields and returns the zap logger to write them with,
+// which has the level of the tenant override matching the attached context, if any
+func (l *logger) prepare(fields []Field) (*zap.Logger, []Field) {
+	fields = l.getContextMetadataAsFields(fields)
+	if l.passContext && l.attachedContext != nil {
+		fields = append(fields, contextField(l.attachedContext))
+	}
+
+	override, ok := matchTenantOverride(l.attachedContext)
+	if !ok {
+		return l.underlyingLogger, fields
+	}
+
+	return override.apply(l.underlyingLogger), append(fields, libraryfield.Mark(zap.String(overrideReasonKey, override.Reason)))
+}
+
 // Debug logs a message with the debug level
 func (l *logger) Debug(msg string, fields ...Field) {
-	updateFields := l.getContextMetadataAsFields(fields)
-	if chunks := chunkOversizedFields(updateFields); chunks != nil {
-		for _, chunkFields := range chunks {
-			l.underlyingLogger.Debug(msg, chunkFields...)
-		}
-		return
-	}
-	l.underlyingLogger.Debug(msg, updateFields...)
+	zapLogger, updateFields := l.prepare(fields)
+	zapLogger.Debug(msg, updateFields...)
 }
 
 // Info logs a message with the info level
 func (l *logger) Info(msg string, fields ...Field) {
-	updateFields := l.getContextMetadataAsFields(fields)
-	if chunks := chunkOversizedFields(updateFields); chunks != nil {
-		for _, chunkFields := range chunks {
-			l.underlyingLogger.Info(msg, chunkFields...)
-		}
-		return
-	}
-	l.underlyingLogger.Info(msg, updateFields...)
+	zapLogger, updateFields := l.prepare(fields)
+	zapLogger.Info(msg, updateFields...)
 }
 
 // Warn logs a message with the warn level
 func (l *logger) Warn(msg string, fields ...Field) {
-	updateFields := l.getContextMetadataAsFields(fields)
-	if chunks := chunkOversizedFields(updateFields); chunks != nil {
-		for _, chunkFields := range chunks {
-			l.underlyingLogger.Warn(msg, chunkFields...)
-		}
-		return
-	}
-	l.underlyingLogger.Warn(msg, updateFields...)
+	zapLogger, updateFields := l.prepare(fields)
+	zapLogger.Warn(msg, updateFields...)
 }
 
 // Error logs a message with the error level
 func (l *logger) Error(msg string, fields ...Field) {
 	trace.SpanFromContext(l.attachedContext).RecordError(errors.New(msg))
 	trace.SpanFromContext(l.attachedContext).SetStatus(codes.Error, msg)
-	updateFields := l.getContextMetadataAsFields(fields)
-	if chunks := chunkOversizedFields(updateFields); chunks != nil {
-		for _, chunkFields := range chunks {
-			l.underlyingLogger.Error(msg, chunkFields...)
-		}
-		return
+	l.errorWithoutSpan(msg, fields...)
+}
+
+// errorWithoutSpan logs a message with the error level without recording it on the span of the
+// attached context, for callers recording the error on the span themselves
+func (l *logger) errorWithoutSpan(msg string, fields ...Field) {
+	zapLogger, updateFields := l.prepare(fields)
+	zapLogger.Error(msg, updateFields...)
+}
+
+// logErrorWithoutSpan logs a message with the error level with l, without recording it on the span
+// if l was created by this package. l may be nil.
+func logErrorWithoutSpan(l Logger, msg string, fields ...Field) {
+	switch l := l.(type) {
+	case nil:
+	case *logger:
+		l.errorWithoutSpan(msg, fields...)
+	default:
+		l.Error(msg, fields...)
 	}
-	l.underlyingLogger.Error(msg, updateFields...)
 }
 
 // Fatal logs a message with the fatal level and then calls os.Exit(1)
 func (l *logger) Fatal(msg string, fields ...Field) {
 	trace.SpanFromContext(l.attachedContext).SetStatus(codes.Error, msg)
-	updateFields := l.getContextMetadataAsFields(fields)
+	zapLogger, updateFields := l.prepare(fields)
 	l.Sync()
 
-	l.underlyingLogger.Fatal(msg, updateFields...)
+	zapLogger.Fatal(msg, updateFields...)
 }
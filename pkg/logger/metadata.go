@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type logConfigCtxKey struct{}
+
+// contextMetadata is the single context value holding the LogConfig metadata attached
+// with WithLogConfig and the custom sections attached with ContextSection.With,
+// along with their fields resolved once at attach time
+type contextMetadata struct {
+	config   LogConfig
+	sections []sectionValue
+	fields   []zapcore.Field
+}
+
+// withConfig returns a copy of m holding config
+func (m *contextMetadata) withConfig(config LogConfig) *contextMetadata {
+	updated := &contextMetadata{config: config}
+	if m != nil {
+		updated.sections = m.sections
+	}
+	updated.fields = updated.appendSectionFields(logConfigPlan.appendFields(reflect.ValueOf(config), nil))
+	return updated
+}
+
+// appendSectionFields appends the non-empty fields of the custom sections in the order they were attached
+func (m *contextMetadata) appendSectionFields(fields []zapcore.Field) []zapcore.Field {
+	for _, s := range m.sections {
+		fields = s.section.plan.appendFields(reflect.ValueOf(s.value), fields)
+	}
+	return fields
+}
+
+// appendSectionAttributes appends the non-empty fields of the custom sections as span attributes
+func (m *contextMetadata) appendSectionAttributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	for _, s := range m.sections {
+		attrs = s.section.plan.appendAttributes(reflect.ValueOf(s.value), attrs)
+	}
+	return attrs
+}
+
+// metadataField is a json-tagged string field of a metadata struct
+type metadataField struct {
+	key   string
+	index []int
+}
+
+// metadataPlan lists the json-tagged string fields of a metadata struct so that
+// values can be extracted without walking the struct type on every log call
+type metadataPlan []metadataField
+
+// logConfigPlan is compiled once for LogConfig
+var logConfigPlan = compileMetadataPlan(reflect.TypeFor[LogConfig](), nil, nil)
+
+// compileMetadataPlan collects the exported json-tagged string fields of t, recursing into nested structs.
+// The key is the name of the json tag, without options such as omitempty.
+func compileMetadataPlan(t reflect.Type, index []int, plan metadataPlan) metadataPlan {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if field.Type.Kind() == reflect.Struct {
+			plan = compileMetadataPlan(field.Type, fieldIndex, plan)
+			continue
+		}
+
+		jsonKey, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonKey == "" || jsonKey == "-" || field.Type.Kind() != reflect.String {
+			continue
+		}
+
+		plan = append(plan, metadataField{key: jsonKey, index: fieldIndex})
+	}
+	return plan
+}
+
+// appendFields appends the non-empty fields of v as zap fields
+func (p metadataPlan) appendFields(v reflect.Value, fields []zapcore.Field) []zapcore.Field {
+	for _, f := range p {
+		if value := v.FieldByIndex(f.index).String(); value != "" {
+			fields = append(fields, libraryfield.Mark(zap.String(f.key, value)))
+		}
+	}
+	return fields
+}
+
+// appendAttributes appends the non-empty fields of v as span attributes
+func (p metadataPlan) appendAttributes(v reflect.Value, attrs []attribute.KeyValue) []attribute.KeyValue {
+	for _, f := range p {
+		if value := v.FieldByIndex(f.index).String(); value != "" {
+			attrs = append(attrs, attribute.String(f.key, value))
+		}
+	}
+	return attrs
+}
+
+// field returns the plan entry for a json key
+func (p metadataPlan) field(key string) (metadataField, bool) {
+	for _, f := range p {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return metadataField{}, false
+}
+
+// merge overwrites the fields of dst with the non-empty fields of src
+func (p metadataPlan) merge(dst, src reflect.Value) {
+	for _, f := range p {
+		if value := src.FieldByIndex(f.index).String(); value != "" {
+			dst.FieldByIndex(f.index).SetString(value)
+		}
+	}
+}
+
+// WithLogConfig returns a copy of ctx with the non-empty fields of cfg merged into
+// the LogConfig metadata attached to ctx. Values set on a child context override
+// those of its parents. The merged metadata is added to every log entry and to
+// span attributes set by SetSpanAttributes.
+func WithLogConfig(ctx context.Context, cfg LogConfig) context.Context {
+	var merged LogConfig
+	metadata, _ := ctx.Value(logConfigCtxKey{}).(*contextMetadata)
+	if metadata != nil {
+		merged = metadata.config
+	}
+	logConfigPlan.merge(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(cfg))
+
+	return context.WithValue(ctx, logConfigCtxKey{}, metadata.withConfig(merged))
+}
+
+// WithRepositoryMetadata merges repository metadata into the LogConfig attached to ctx
+func WithRepositoryMetadata(ctx context.Context, repository Repository) context.Context {
+	return WithLogConfig(ctx, LogConfig{Repository: repository})
+}
+
+// WithServiceMetadata merges service metadata into the LogConfig attached to ctx
+func WithServiceMetadata(ctx context.Context, service Service) context.Context {
+	return WithLogConfig(ctx, LogConfig{Service: service})
+}
+
+// WithToolMetadata merges tool metadata into the LogConfig attached to ctx
+func WithToolMetadata(ctx context.Context, tool Tool) context.Context {
+	return WithLogConfig(ctx, LogConfig{Tool: tool})
+}
+
+// WithPlatformMetadata merges platform metadata into the LogConfig attached to ctx
+func WithPlatformMetadata(ctx context.Context, platform Platform) context.Context {
+	return WithLogConfig(ctx, LogConfig{Platform: platform})
+}
+
+// LogConfigFromContext returns the LogConfig metadata attached to ctx.
+// Metadata set with WithLogConfig overrides the LogConfig of the NullifyContext, if any.
+func LogConfigFromContext(ctx context.Context) LogConfig {
+	if ctx == nil {
+		return LogConfig{}
+	}
+
+	var cfg LogConfig
+	if nullifyContext, ok := ctx.Value(nullifyContextKey).(*NullifyContext); ok {
+		cfg = nullifyContext.logConfig()
+	}
+
+	if metadata, ok := ctx.Value(logConfigCtxKey{}).(*contextMetadata); ok {
+		logConfigPlan.merge(reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(metadata.config))
+	}
+
+	return cfg
+}
+
+// appendMetadataFields appends the LogConfig metadata attached to ctx as fields.
+// Fields resolved by WithLogConfig are reused as-is unless the NullifyContext
+// also holds a LogConfig that needs merging.
+func appendMetadataFields(ctx context.Context, fields []zapcore.Field) []zapcore.Field {
+	if ctx == nil {
+		return fields
+	}
+
+	metadata, hasMetadata := ctx.Value(logConfigCtxKey{}).(*contextMetadata)
+
+	if nullifyContext, ok := ctx.Value(nullifyContextKey).(*NullifyContext); ok {
+		if cfg := nullifyContext.logConfig(); cfg != (LogConfig{}) {
+			if !hasMetadata {
+				return logConfigPlan.appendFields(reflect.ValueOf(cfg), fields)
+			}
+			logConfigPlan.merge(reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(metadata.config))
+			return metadata.appendSectionFields(logConfigPlan.appendFields(reflect.ValueOf(cfg), fields))
+		}
+	}
+
+	if hasMetadata {
+		return append(fields, metadata.fields...)
+	}
+
+	return fields
+}
+
+// metadataAttributes returns the LogConfig metadata and custom sections attached to ctx as span attributes
+func metadataAttributes(ctx context.Context) []attribute.KeyValue {
+	attrs := logConfigAttributes(LogConfigFromContext(ctx))
+	if metadata, ok := ctx.Value(logConfigCtxKey{}).(*contextMetadata); ok {
+		attrs = metadata.appendSectionAttributes(attrs)
+	}
+	return attrs
+}
+
+// logConfigAttributes returns the non-empty fields of cfg as span attributes
+func logConfigAttributes(cfg LogConfig) []attribute.KeyValue {
+	return logConfigPlan.appendAttributes(reflect.ValueOf(cfg), nil)
+}
@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithLogConfigMerges(t *testing.T) {
+	ctx := WithRepositoryMetadata(t.Context(), Repository{Name: "logger", Owner: "nullify"})
+	ctx = WithServiceMetadata(ctx, Service{Name: "scanner"})
+	child := WithLogConfig(ctx, LogConfig{
+		Repository: Repository{Name: "other"},
+		Tool:       Tool{Name: "semgrep"},
+	})
+	child = WithPlatformMetadata(child, Platform{Name: "github"})
+
+	assert.Equal(t, LogConfig{
+		Repository: Repository{Name: "logger", Owner: "nullify"},
+		Service:    Service{Name: "scanner"},
+	}, LogConfigFromContext(ctx))
+
+	assert.Equal(t, LogConfig{
+		Repository: Repository{Name: "other", Owner: "nullify"},
+		Service:    Service{Name: "scanner"},
+		Tool:       Tool{Name: "semgrep"},
+		Platform:   Platform{Name: "github"},
+	}, LogConfigFromContext(child))
+}
+
+func TestLogConfigFromContextOverridesNullifyContext(t *testing.T) {
+	ctx, nullifyContext := GetNullifyContext(tracer.NewContext(t.Context(), sdktrace.NewTracerProvider(), "test"))
+	nullifyContext.LogConfig.Repository = Repository{Name: "from-nullify-context", Owner: "nullify"}
+
+	ctx = WithToolMetadata(ctx, Tool{Name: "semgrep"})
+	ctx = WithRepositoryMetadata(ctx, Repository{Name: "from-metadata"})
+
+	assert.Equal(t, LogConfig{
+		Repository: Repository{Name: "from-metadata", Owner: "nullify"},
+		Tool:       Tool{Name: "semgrep"},
+	}, LogConfigFromContext(ctx))
+}
+
+func TestLogConfigPlan(t *testing.T) {
+	keys := make([]string, len(logConfigPlan))
+	for i, f := range logConfigPlan {
+		keys[i] = f.key
+	}
+
+	assert.Len(t, keys, 23)
+	assert.Equal(t, "repositoryName", keys[0])
+	assert.Contains(t, keys, "serviceEvent")
+	assert.Contains(t, keys, "toolStatus")
+	assert.Equal(t, "platformComponent", keys[len(keys)-1])
+}
+
+func TestLogEntriesContainMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	ctx = WithRepositoryMetadata(ctx, Repository{Name: "logger", InstallationID: "42"})
+	ctx = WithToolMetadata(ctx, Tool{Name: "semgrep"})
+	L(ctx).Info("hello")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "logger", entry["repositoryName"])
+	assert.Equal(t, "42", entry["installationId"])
+	assert.Equal(t, "semgrep", entry["toolName"])
+	assert.NotContains(t, entry, "repositoryOwner")
+}
+
+func TestSetSpanAttributesUsesMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	ctx = tracer.NewContext(ctx, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), "test-tracer")
+	ctx, _ = GetNullifyContext(ctx)
+	ctx = WithRepositoryMetadata(ctx, Repository{Name: "logger"})
+
+	L(ctx).SetSpanAttributes("work")
+
+	var found bool
+	for _, s := range recorder.Started() {
+		if s.Name() != "work" {
+			continue
+		}
+		found = true
+		assert.Contains(t, s.Attributes(), attribute.String("repositoryName", "logger"))
+		assert.Contains(t, s.Attributes(), attribute.String("vcs.repository.name", "logger"))
+	}
+	assert.True(t, found, "span not started")
+}
+
+func TestSetSpanAttributesWithoutNullifyContext(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	ctx = tracer.NewContext(ctx, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), "test-tracer")
+	ctx = WithServiceMetadata(ctx, Service{Name: "scanner"})
+
+	var spanCtx context.Context
+	require.NotPanics(t, func() { spanCtx = L(ctx).SetSpanAttributes("work") })
+	require.Len(t, recorder.Started(), 1)
+	assert.Equal(t, recorder.Started()[0].SpanContext(), trace.SpanFromContext(spanCtx).SpanContext())
+	assert.Contains(t, recorder.Started()[0].Attributes(), attribute.String("service.name", "scanner"))
+
+	assert.NotPanics(t, func() { L(ctx).NewChild().SetSpanAttributes("no-context") })
+}
+
+func BenchmarkInfoWithoutMetadata(b *testing.B) {
+	ctx, err := ConfigureProductionLogger(b.Context(), "info", io.Discard)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		L(ctx).Info("hello", String("key", "value"))
+	}
+}
+
+func BenchmarkInfoWithMetadata(b *testing.B) {
+	ctx, err := ConfigureProductionLogger(b.Context(), "info", io.Discard)
+	require.NoError(b, err)
+
+	ctx = WithRepositoryMetadata(ctx, Repository{Name: "logger", Owner: "nullify", ID: "1", InstallationID: "42", OrganizationID: "org"})
+	ctx = WithServiceMetadata(ctx, Service{Name: "scanner", Event: "push"})
+	ctx = WithToolMetadata(ctx, Tool{Name: "semgrep"})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		L(ctx).Info("hello", String("key", "value"))
+	}
+}
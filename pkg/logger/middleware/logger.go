@@ -2,6 +2,7 @@
 package middleware
 
 import (
+	"io"
 	"net/http"
 	"net/url"
 	"runtime/debug"
@@ -9,12 +10,18 @@ import (
 	"time"
 
 	"github.com/nullify-platform/logger/pkg/logger"
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type responseWriter struct {
 	http.ResponseWriter
 
 	StatusCode int
+
+	// capture records the response body when body capture is enabled for the request
+	capture *logger.BodyCapture
+	body    *logger.BodyRecorder
 }
 
 func (rw *responseWriter) Header() http.Header {
@@ -22,7 +29,26 @@ func (rw *responseWriter) Header() http.Header {
 }
 
 func (rw *responseWriter) Write(data []byte) (int, error) {
-	return rw.ResponseWriter.Write(data)
+	if rw.capture != nil && rw.body == nil {
+		rw.body = rw.capture.NewRecorder(rw.Header().Get("Content-Type"))
+	}
+	n, err := rw.ResponseWriter.Write(data)
+	if rw.body != nil {
+		_, _ = rw.body.Write(data[:n])
+	}
+	return n, err
+}
+
+// Flush sends buffered data to the client, so streaming handlers keep working with body capture
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
 
 func (rw *responseWriter) WriteHeader(statusCode int) {
@@ -48,9 +74,9 @@ func (m httpRequestMetadata) ToLogFields() []logger.Field {
 		logger.String("method", m.Method),
 		logger.String("path", m.Path),
 		logger.Any("query", m.Query),
-		logger.Int("statusCode", m.StatusCode),
-		logger.Any("requestHeaders", m.RequestHeaders),
-		logger.Any("responseHeaders", m.ResponseHeaders),
+		libraryfield.Mark(logger.Int("statusCode", m.StatusCode)),
+		libraryfield.Mark(logger.Any("requestHeaders", m.RequestHeaders)),
+		libraryfield.Mark(logger.Any("responseHeaders", m.ResponseHeaders)),
 		logger.Duration("duration", m.Duration),
 	}
 	return fields
@@ -58,6 +84,18 @@ func (m httpRequestMetadata) ToLogFields() []logger.Field {
 
 // LoggingMiddleware logs the incoming request and the outgoing response and adds relevant tracing information
 func LoggingMiddleware(next http.Handler) http.Handler {
+	return loggingHandler(next, nil)
+}
+
+// LoggingMiddlewareWithBodyCapture is LoggingMiddleware that also logs the request and response
+// bodies of the requests selected by capture, as they are read and written by the handler
+func LoggingMiddlewareWithBodyCapture(capture logger.BodyCapture) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return loggingHandler(next, &capture)
+	}
+}
+
+func loggingHandler(next http.Handler, capture *logger.BodyCapture) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
@@ -97,8 +135,21 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			RequestHeaders: reqHeaders,
 		}
 
-		start := time.Now()
 		rw := &responseWriter{ResponseWriter: w}
+
+		var requestBody *logger.BodyRecorder
+		if capture.Captures(r) {
+			rw.capture = capture
+			if r.Body != nil && r.Body != http.NoBody {
+				requestBody = capture.NewRecorder(r.Header.Get("Content-Type"))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.TeeReader(r.Body, requestBody), r.Body}
+			}
+		}
+
+		start := time.Now()
 		next.ServeHTTP(rw, r.WithContext(ctx))
 		duration := time.Since(start)
 
@@ -114,16 +165,25 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		metadata.Duration = duration
 
 		if r.URL.EscapedPath() != "/healthcheck" {
+			fields := metadata.ToLogFields()
+			span := trace.SpanFromContext(ctx)
+			if requestBody != nil {
+				fields = append(fields, capture.Attach(span, "requestBody", requestBody.Captured())...)
+			}
+			if rw.body != nil {
+				fields = append(fields, capture.Attach(span, "responseBody", rw.body.Captured())...)
+			}
+
 			log := logger.L(ctx)
 			switch {
 			case metadata.StatusCode >= 500:
 				// Server error
-				log.Error("request summary", metadata.ToLogFields()...)
+				log.Error("request summary", fields...)
 			case metadata.StatusCode >= 400:
 				// Client error
-				log.Warn("request summary", metadata.ToLogFields()...)
+				log.Warn("request summary", fields...)
 			default:
-				log.Info("request summary", metadata.ToLogFields()...)
+				log.Info("request summary", fields...)
 			}
 		}
 	})
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nullify-platform/logger/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggingMiddlewareWithBodyCapture(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, err := logger.ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	handler := LoggingMiddlewareWithBodyCapture(logger.BodyCapture{Match: logger.MatchPathPrefixes("/login")})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"accessToken":"abc",`))
+			http.NewResponseController(w).Flush()
+			_, _ = w.Write([]byte(`"expiresIn":3600}`))
+		}),
+	)
+
+	for _, path := range []string{"/login", "/users"} {
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, path, strings.NewReader("username=ana&password=hunter2"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		assert.True(t, res.Flushed)
+		assert.Equal(t, `{"accessToken":"abc","expiresIn":3600}`, res.Body.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var login, users map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &login))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &users))
+
+	assert.Equal(t, "password=%5BREDACTED%5D&username=ana", login["requestBody"].(map[string]any)["content"])
+	assert.Equal(t, `{"accessToken":"[REDACTED]","expiresIn":3600}`, login["responseBody"].(map[string]any)["content"])
+	assert.NotContains(t, users, "requestBody")
+	assert.NotContains(t, users, "responseBody")
+}
@@ -0,0 +1,292 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap/zapcore"
+)
+
+// FieldNaming is the key naming convention applied to library-generated log fields
+type FieldNaming string
+
+const (
+	// FieldNamingCompat keeps the historical mix of key styles (default)
+	FieldNamingCompat FieldNaming = "compat"
+	// FieldNamingSnake renders library keys as snake_case, e.g. repository_name
+	FieldNamingSnake FieldNaming = "snake"
+	// FieldNamingCamel renders library keys as camelCase, e.g. repositoryName
+	FieldNamingCamel FieldNaming = "camel"
+	// FieldNamingOTel renders library keys as dotted OpenTelemetry semantic convention
+	// names where one exists (e.g. vcs.repository.name), and snake_case otherwise
+	FieldNamingOTel FieldNaming = "otel"
+)
+
+// fieldNamingEnvVar selects the FieldNaming used by ConfigureProductionLogger and ConfigureDevelopmentLogger
+const fieldNamingEnvVar = "LOG_FIELD_NAMING"
+
+// ParseFieldNaming parses a field naming convention name
+func ParseFieldNaming(s string) (FieldNaming, error) {
+	switch naming := FieldNaming(strings.ToLower(strings.TrimSpace(s))); naming {
+	case "":
+		return FieldNamingCompat, nil
+	case FieldNamingCompat, FieldNamingSnake, FieldNamingCamel, FieldNamingOTel:
+		return naming, nil
+	default:
+		return FieldNamingCompat, fmt.Errorf("unknown field naming %q", s)
+	}
+}
+
+// otelFieldKeys maps library-generated keys onto OpenTelemetry semantic convention names.
+// Keys without a semantic convention are namespaced under nullify.
+var otelFieldKeys = map[string]string{
+	"trace-id":        "trace_id",
+	"span-id":         "span_id",
+	"service.version": "service.version",
+	"service.name":    "service.name",
+
+	// Repository
+	"repositoryName":  "vcs.repository.name",
+	"repositoryOwner": "vcs.owner.name",
+	"repositoryId":    "vcs.repository.id",
+	"commitId":        "vcs.ref.head.revision",
+	"prNumber":        "vcs.change.id",
+	"branchId":        "vcs.ref.head.id",
+	"branchName":      "vcs.ref.head.name",
+	"installationId":  "nullify.installation.id",
+	"appId":           "nullify.app.id",
+	"action":          "nullify.action",
+	"projectName":     "nullify.project.name",
+	"projectId":       "nullify.project.id",
+	"organizationId":  "nullify.organization.id",
+	"startCommitSha":  "nullify.commit_range.start",
+	"endCommitSha":    "nullify.commit_range.end",
+	"cloneUrl":        "vcs.repository.url.full",
+
+	// Service
+	"serviceName":     "nullify.service.name",
+	"serviceCategory": "nullify.service.category",
+	"serviceEvent":    "nullify.service.event",
+
+	// Tool
+	"toolName":   "nullify.tool.name",
+	"toolStatus": "nullify.tool.status",
+
+	// Platform
+	"platformName":      "nullify.platform.name",
+	"platformComponent": "nullify.platform.component",
+
+	// fields.go
+	"error_type":      "error.type",
+	"error_message":   "exception.message",
+	"error_traceback": "exception.stacktrace",
+
+	// scan.go
+	"scan_id":    "nullify.scan.id",
+	"scan_phase": "nullify.scan.phase",
+
+	// overrides.go
+	"log_override_reason": "nullify.log.override_reason",
+
+	// oversize.go
+	"log_offload_error": "nullify.log.offload_error",
+
+	// http.go and middleware
+	"statusCode": "http.response.status_code",
+}
+
+// Key returns the key of a field created by this library rendered in the naming convention
+func (n FieldNaming) Key(key string) string {
+	if n == FieldNamingCompat || n == "" {
+		return key
+	}
+
+	if n == FieldNamingOTel {
+		if otelKey, ok := otelFieldKeys[key]; ok {
+			return otelKey
+		}
+	}
+
+	return n.convert(key)
+}
+
+// suffixKey appends a library-generated suffix such as _encoding to a user key, in the naming convention
+func (n FieldNaming) suffixKey(key, suffix string) string {
+	switch n {
+	case FieldNamingCamel:
+		return key + n.convert("x" + suffix)[1:]
+	case FieldNamingOTel:
+		return key + "." + suffix[1:]
+	default:
+		return key + suffix
+	}
+}
+
+// convert renders key in snake_case or camelCase, also used for nested keys in OTel mode
+func (n FieldNaming) convert(key string) string {
+	words := splitKeyWords(key)
+	if n != FieldNamingCamel {
+		return strings.Join(words, "_")
+	}
+
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+// splitKeyWords splits a key on separators and camelCase boundaries into lowercase words
+func splitKeyWords(key string) []string {
+	var words []string
+	var current strings.Builder
+	var prev rune
+
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range key {
+		switch {
+		case r == '-' || r == '_' || r == '.':
+			flush()
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			flush()
+			current.WriteRune(unicode.ToLower(r))
+		default:
+			current.WriteRune(unicode.ToLower(r))
+		}
+		prev = r
+	}
+	flush()
+
+	return words
+}
+
+// renameField returns f with its key, and the nested keys of library objects, in the naming convention
+// if it was created by this library, see libraryfield.Mark
+func (n FieldNaming) renameField(f zapcore.Field) zapcore.Field {
+	f, marked, object := libraryfield.Unmark(f)
+	if !marked {
+		return f
+	}
+
+	if nested, ok := f.Interface.(map[string]any); ok && object {
+		renamed := make(map[string]any, len(nested))
+		for k, v := range nested {
+			renamed[n.convert(k)] = v
+		}
+		f.Interface = renamed
+	}
+
+	f.Key = n.Key(f.Key)
+	return f
+}
+
+func (n FieldNaming) renameFields(fields []zapcore.Field) []zapcore.Field {
+	renamed := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		renamed[i] = n.renameField(f)
+	}
+	return renamed
+}
+
+// namingCore is a zapcore.Core that renames the keys of fields created by this library at encode time
+type namingCore struct {
+	zapcore.Core
+
+	naming FieldNaming
+}
+
+// newNamingCore wraps core so library-generated keys follow naming.
+// FieldNamingCompat returns core unchanged.
+func newNamingCore(core zapcore.Core, naming FieldNaming) zapcore.Core {
+	if naming == FieldNamingCompat || naming == "" {
+		return core
+	}
+	return &namingCore{Core: core, naming: naming}
+}
+
+func (c *namingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namingCore{Core: c.Core.With(c.naming.renameFields(fields)), naming: c.naming}
+}
+
+func (c *namingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *namingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.naming.renameFields(fields))
+}
+
+// SpanAttributeNaming selects which keys SetSpanAttributes uses for LogConfig metadata
+type SpanAttributeNaming string
+
+const (
+	// SpanAttributeNamingJSON sets the json tag keys only, e.g. repositoryName
+	SpanAttributeNamingJSON SpanAttributeNaming = "json"
+	// SpanAttributeNamingSemconv sets OpenTelemetry semantic convention keys only, e.g. vcs.repository.name
+	SpanAttributeNamingSemconv SpanAttributeNaming = "semconv"
+	// SpanAttributeNamingBoth sets both the json tag and semantic convention keys (default)
+	SpanAttributeNamingBoth SpanAttributeNaming = "both"
+)
+
+// spanAttributeNamingEnvVar selects the SpanAttributeNaming used by SetSpanAttributes
+const spanAttributeNamingEnvVar = "SPAN_ATTRIBUTE_NAMING"
+
+// ParseSpanAttributeNaming parses a span attribute naming name
+func ParseSpanAttributeNaming(s string) (SpanAttributeNaming, error) {
+	switch naming := SpanAttributeNaming(strings.ToLower(strings.TrimSpace(s))); naming {
+	case "":
+		return SpanAttributeNamingBoth, nil
+	case SpanAttributeNamingJSON, SpanAttributeNamingSemconv, SpanAttributeNamingBoth:
+		return naming, nil
+	default:
+		return SpanAttributeNamingBoth, fmt.Errorf("unknown span attribute naming %q", s)
+	}
+}
+
+// spanSemconvKeys overrides otelFieldKeys for span attributes. Unlike log entries,
+// spans do not already carry service.name as a field, so the service name can use it.
+var spanSemconvKeys = map[string]string{
+	"serviceName": "service.name",
+}
+
+// semconvAttributeKey returns the semantic convention key for a LogConfig json tag key
+func semconvAttributeKey(key string) (string, bool) {
+	if semconvKey, ok := spanSemconvKeys[key]; ok {
+		return semconvKey, true
+	}
+	semconvKey, ok := otelFieldKeys[key]
+	return semconvKey, ok
+}
+
+// attributes renames or duplicates LogConfig attributes according to the naming, both if unset
+func (n SpanAttributeNaming) attributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if n == SpanAttributeNamingJSON {
+		return attrs
+	}
+	if n == "" {
+		n = SpanAttributeNamingBoth
+	}
+
+	named := make([]attribute.KeyValue, 0, len(attrs)*2)
+	for _, attr := range attrs {
+		semconvKey, ok := semconvAttributeKey(string(attr.Key))
+		if n == SpanAttributeNamingBoth || !ok {
+			named = append(named, attr)
+		}
+		if ok {
+			named = append(named, attribute.KeyValue{Key: attribute.Key(semconvKey), Value: attr.Value})
+		}
+	}
+	return named
+}
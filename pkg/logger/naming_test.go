@@ -0,0 +1,167 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestParseFieldNaming(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected FieldNaming
+		wantErr  bool
+	}{
+		{"", FieldNamingCompat, false},
+		{"compat", FieldNamingCompat, false},
+		{"SNAKE", FieldNamingSnake, false},
+		{" camel ", FieldNamingCamel, false},
+		{"otel", FieldNamingOTel, false},
+		{"kebab", FieldNamingCompat, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			naming, err := ParseFieldNaming(tt.input)
+			assert.Equal(t, tt.expected, naming)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestFieldNamingKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		compat string
+		snake  string
+		camel  string
+		otel   string
+	}{
+		{"trace-id", "trace-id", "trace_id", "traceId", "trace_id"},
+		{"service.version", "service.version", "service_version", "serviceVersion", "service.version"},
+		{"repositoryName", "repositoryName", "repository_name", "repositoryName", "vcs.repository.name"},
+		{"cloneUrl", "cloneUrl", "clone_url", "cloneUrl", "vcs.repository.url.full"},
+		{"error_type", "error_type", "error_type", "errorType", "error.type"},
+		{"tool_call", "tool_call", "tool_call", "toolCall", "tool_call"},
+		{"statusCode", "statusCode", "status_code", "statusCode", "http.response.status_code"},
+		{"duration_ms", "duration_ms", "duration_ms", "durationMs", "duration_ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.compat, FieldNamingCompat.Key(tt.key))
+			assert.Equal(t, tt.snake, FieldNamingSnake.Key(tt.key))
+			assert.Equal(t, tt.camel, FieldNamingCamel.Key(tt.key))
+			assert.Equal(t, tt.otel, FieldNamingOTel.Key(tt.key))
+		})
+	}
+}
+
+func TestFieldNamingRenameField(t *testing.T) {
+	nested := FieldNamingCamel.renameField(WithToolCall(ToolCallFields{ToolName: "semgrep", Status: "ok"}))
+	assert.Equal(t, "toolCall", nested.Key)
+	assert.Equal(t, map[string]any{"toolName": "semgrep", "status": "ok"}, nested.Interface)
+
+	// caller keys that look like chunk metadata are not renamed
+	user := FieldNamingCamel.renameField(Int("file_chunk", 1))
+	assert.Equal(t, "file_chunk", user.Key)
+
+	user = FieldNamingOTel.renameField(String("user_chunk_id", "0123456789abcdef"))
+	assert.Equal(t, "user_chunk_id", user.Key)
+}
+
+func TestFieldNamingSuffixKey(t *testing.T) {
+	assert.Equal(t, "body_total_chunks", FieldNamingCompat.suffixKey("body", "_total_chunks"))
+	assert.Equal(t, "body_chunk_id", FieldNamingSnake.suffixKey("body", "_chunk_id"))
+	assert.Equal(t, "bodyTotalChunks", FieldNamingCamel.suffixKey("body", "_total_chunks"))
+	assert.Equal(t, "my_body.chunk", FieldNamingOTel.suffixKey("my_body", "_chunk"))
+	assert.Equal(t, "body.chunk_id", FieldNamingOTel.suffixKey("body", "_chunk_id"))
+}
+
+func TestLoggerOutputUsesFieldNaming(t *testing.T) {
+	t.Setenv("LOG_FIELD_NAMING", "otel")
+	t.Setenv("OTEL_SERVICE_NAME", "test-svc")
+
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	L(ctx).Info("hello", WithErrorInfo(ErrorFields{Type: ErrorTypeNetwork, Message: "refused"})[0], String("custom_key", "value"))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	assert.Equal(t, "test-svc", entry["service.name"])
+	assert.Equal(t, "0.0.0", entry["service.version"])
+	assert.Equal(t, "network_error", entry["error.type"])
+	assert.Equal(t, "value", entry["custom_key"])
+	assert.NotContains(t, entry, "error_type")
+}
+
+func TestLoggerOutputKeepsCallerKeys(t *testing.T) {
+	t.Setenv("LOG_FIELD_NAMING", "otel")
+
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	// caller keys that match library keys are not renamed
+	L(ctx).Info("hello", String("action", "opened"), Int("statusCode", 3), Any("repository", map[string]any{"repoName": "api"}))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	assert.Equal(t, "opened", entry["action"])
+	assert.Equal(t, float64(3), entry["statusCode"])
+	assert.Equal(t, map[string]any{"repoName": "api"}, entry["repository"])
+	assert.NotContains(t, entry, "nullify.action")
+	assert.NotContains(t, entry, "http.response.status_code")
+}
+
+func TestSpanAttributeNaming(t *testing.T) {
+	attrs := []attribute.KeyValue{
+		attribute.String("repositoryName", "logger"),
+		attribute.String("serviceName", "scanner"),
+		attribute.String("unmapped", "value"),
+	}
+
+	assert.Equal(t, attrs, SpanAttributeNamingJSON.attributes(attrs))
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("vcs.repository.name", "logger"),
+		attribute.String("service.name", "scanner"),
+		attribute.String("unmapped", "value"),
+	}, SpanAttributeNamingSemconv.attributes(attrs))
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("repositoryName", "logger"),
+		attribute.String("vcs.repository.name", "logger"),
+		attribute.String("serviceName", "scanner"),
+		attribute.String("service.name", "scanner"),
+		attribute.String("unmapped", "value"),
+	}, SpanAttributeNamingBoth.attributes(attrs))
+
+	naming, err := ParseSpanAttributeNaming("")
+	assert.NoError(t, err)
+	assert.Equal(t, SpanAttributeNamingBoth, naming)
+
+	_, err = ParseSpanAttributeNaming("dotted")
+	assert.Error(t, err)
+}
+
+func TestSpanAttributeNamingPerLogger(t *testing.T) {
+	t.Setenv("SPAN_ATTRIBUTE_NAMING", "json")
+	jsonCtx, err := ConfigureProductionLogger(t.Context(), "info", io.Discard)
+	require.NoError(t, err)
+
+	t.Setenv("SPAN_ATTRIBUTE_NAMING", "semconv")
+	semconvCtx, err := ConfigureProductionLogger(t.Context(), "info", io.Discard)
+	require.NoError(t, err)
+
+	// configuring another logger does not change the naming of loggers already configured
+	assert.Equal(t, SpanAttributeNamingJSON, L(jsonCtx).(*logger).spanAttributeNaming)
+	assert.Equal(t, SpanAttributeNamingSemconv, L(semconvCtx).(*logger).spanAttributeNaming)
+}
@@ -2,14 +2,12 @@ package logger
 
 import (
 	"context"
-	"reflect"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/nullify-platform/logger/pkg/logger/tracer"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
-	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -18,11 +16,49 @@ type nullifyContextKeyType string
 
 const nullifyContextKey nullifyContextKeyType = "NullifyContext"
 
-// NullifyContext holds a mutable tree of BotActions and other useful data
+// NullifyContext holds the AWS config, current span and LogConfig of a unit of work.
+// It may be shared between goroutines: use Update, Snapshot and CurrentSpan rather
+// than the fields directly once the context has been handed to other goroutines.
+// Child contexts created by SetSpanAttributes receive their own copy, so changes
+// made through a child do not leak into its parent.
 type NullifyContext struct {
 	AWSConfig aws.Config
 	Span      trace.Span
 	LogConfig LogConfig
+
+	mu sync.RWMutex
+}
+
+// Update calls fn with the LogConfig while holding the write lock
+func (n *NullifyContext) Update(fn func(*LogConfig)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	fn(&n.LogConfig)
+}
+
+// Snapshot returns an independent copy of the NullifyContext
+func (n *NullifyContext) Snapshot() *NullifyContext {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return &NullifyContext{
+		AWSConfig: n.AWSConfig,
+		Span:      n.Span,
+		LogConfig: n.LogConfig,
+	}
+}
+
+// CurrentSpan returns the span of the NullifyContext
+func (n *NullifyContext) CurrentSpan() trace.Span {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.Span
+}
+
+// logConfig returns a copy of the LogConfig under the read lock
+func (n *NullifyContext) logConfig() LogConfig {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.LogConfig
 }
 
 // LogConfig holds configuration for logging
@@ -86,79 +122,57 @@ func GetNullifyContext(ctx context.Context) (context.Context, *NullifyContext) {
 	}
 }
 
-// GetAWSConfig retrieves the AWS configuration from the context or loads it if not present
-func GetAWSConfig(ctx context.Context) (aws.Config, error) {
-	_, nullifyContext := GetNullifyContext(ctx)
+// AWSConfigOption configures the aws.Config returned by GetAWSConfig
+type AWSConfigOption func(*awsConfigOptions)
 
-	if nullifyContext.AWSConfig.Region == "" {
-		awsConfig, err := config.LoadDefaultConfig(ctx)
-		if err != nil {
-			L(ctx).Error("error loading AWS config", Err(err))
-			return aws.Config{}, err
-		}
-
-		nullifyContext.AWSConfig = awsConfig
-	}
-
-	return nullifyContext.AWSConfig, nil
+type awsConfigOptions struct {
+	instrument bool
 }
 
-// contextKey is a custom type for context keys
-type contextKey string
-
-// getContextMetadataAsFields extracts fields from the attached context
-func (l *logger) getContextMetadataAsFields(fields []zapcore.Field) []zapcore.Field {
-	return extractFieldsFromStruct(l.attachedContext, reflect.ValueOf(LogConfig{}), fields)
-}
-
-// extractFieldsFromStruct extracts fields from a struct and appends them as zap fields
-func extractFieldsFromStruct(ctx context.Context, v reflect.Value, fields []zapcore.Field) []zapcore.Field {
-	if ctx == nil {
-		return fields
-	}
-
-	if v.Kind() == reflect.Pointer {
-		v = v.Elem()
+// WithAWSInstrumentation adds OTel middleware to the returned config, see tracer.InstrumentAWSConfig.
+// The config cached in the NullifyContext is left unchanged.
+func WithAWSInstrumentation() AWSConfigOption {
+	return func(o *awsConfigOptions) {
+		o.instrument = true
 	}
+}
 
-	if v.Kind() != reflect.Struct {
-		return fields
+// GetAWSConfig retrieves the AWS configuration from the context or loads it if not present
+func GetAWSConfig(ctx context.Context, opts ...AWSConfigOption) (aws.Config, error) {
+	var options awsConfigOptions
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	t := v.Type()
-
-	// Iterate through all struct fields
-	for i := 0; i < v.NumField(); i++ {
-		field := t.Field(i)
-		fieldValue := v.Field(i)
-
-		// If the field is a struct, recurse into it
-		if fieldValue.Kind() == reflect.Struct || (fieldValue.Kind() == reflect.Pointer && fieldValue.Elem().Kind() == reflect.Struct) {
-			fields = extractFieldsFromStruct(ctx, fieldValue, fields)
-			continue
-		}
+	_, nullifyContext := GetNullifyContext(ctx)
 
-		jsonKey := field.Tag.Get("json")
+	nullifyContext.mu.RLock()
+	awsConfig := nullifyContext.AWSConfig
+	nullifyContext.mu.RUnlock()
 
-		// Skip fields without a JSON tag
-		if jsonKey == "" || jsonKey == "-" {
-			continue
+	if awsConfig.Region == "" {
+		loaded, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			L(ctx).Error("error loading AWS config", Err(err))
+			return aws.Config{}, err
 		}
 
-		// Retrieve the value from context using the field name as the key
-		key := contextKey(field.Name)
-		value := ctx.Value(key)
-		if value == nil {
-			continue
-		}
+		nullifyContext.mu.Lock()
+		nullifyContext.AWSConfig = loaded
+		nullifyContext.mu.Unlock()
+		awsConfig = loaded
+	}
 
-		if value, ok := value.(string); ok && value != "" {
-			// Append zap field
-			fields = append(fields, zap.String(jsonKey, value))
-		}
+	if options.instrument {
+		awsConfig = tracer.InstrumentAWSConfig(awsConfig)
 	}
 
-	return fields
+	return awsConfig, nil
+}
+
+// getContextMetadataAsFields appends the LogConfig metadata of the attached context as fields
+func (l *logger) getContextMetadataAsFields(fields []zapcore.Field) []zapcore.Field {
+	return appendMetadataFields(l.attachedContext, fields)
 }
 
 // TODO: This is a temporary function to get the function name. We need to fine tune it to get the function name as there are some edge cases and we need to handle them
@@ -186,54 +200,29 @@ func extractFieldsFromStruct(ctx context.Context, v reflect.Value, fields []zapc
 // 	return fullName
 // }
 
-// SetSpanAttributes sets attributes on the current span based on the LogConfig
+// SetSpanAttributes starts a new span and sets attributes on it based on the LogConfig metadata and custom sections of the attached context.
+// Attribute keys follow SPAN_ATTRIBUTE_NAMING: json tag keys, semantic convention keys, or both (default).
 func (l *logger) SetSpanAttributes(spanName string) context.Context {
-	nullifyContext := l.attachedContext.Value(nullifyContextKey).(*NullifyContext)
-	newContext, span := tracer.FromContext(l.attachedContext).Start(l.attachedContext, spanName)
-	// Update both the span and context in one operation
-	nullifyContext.Span = span
-	l.attachedContext = context.WithValue(newContext, nullifyContextKey, nullifyContext)
-
-	v := reflect.ValueOf(nullifyContext.LogConfig)
-	t := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		fieldType := t.Field(i)
-		// If the field is a struct, recurse into it
-		if field.Kind() == reflect.Struct {
-			l.setStructAttributes(field)
-			continue
-		}
-
-		jsonKey := fieldType.Tag.Get("json")
-		if jsonKey == "" || jsonKey == "-" {
-			continue
-		}
+	ctx := l.attachedContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
-		nullifyContext.Span.SetAttributes(attribute.String(jsonKey, field.String()))
-		l.attachedContext = context.WithValue(l.attachedContext, nullifyContextKey, nullifyContext)
+	t := tracer.FromContext(ctx)
+	if t == nil {
+		t = trace.SpanFromContext(ctx).TracerProvider().Tracer("")
 	}
-	return l.attachedContext
-}
-
-// setStructAttributes sets attributes for struct fields recursively
-func (l *logger) setStructAttributes(v reflect.Value) {
-	t := v.Type()
-	nullifyContext := l.attachedContext.Value(nullifyContextKey).(*NullifyContext)
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		fieldType := t.Field(i)
-		// If the field is a struct, recurse into it
-		if field.Kind() == reflect.Struct {
-			l.setStructAttributes(field)
-			continue
-		}
-		jsonKey := fieldType.Tag.Get("json")
-		if jsonKey == "" || jsonKey == "-" {
-			continue
-		}
-		if field.String() != "" {
-			nullifyContext.Span.SetAttributes(attribute.String(jsonKey, field.String()))
-		}
+
+	ctx, span := t.Start(ctx, spanName)
+	span.SetAttributes(l.spanAttributeNaming.attributes(metadataAttributes(ctx))...)
+
+	if nullifyContext, ok := ctx.Value(nullifyContextKey).(*NullifyContext); ok {
+		// copy-on-write so goroutines sharing the parent context keep their span
+		child := nullifyContext.Snapshot()
+		child.Span = span
+		ctx = context.WithValue(ctx, nullifyContextKey, child)
 	}
+
+	l.attachedContext = ctx
+	return ctx
 }
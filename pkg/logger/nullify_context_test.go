@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNullifyContextUpdateAndSnapshot(t *testing.T) {
+	nullifyContext := &NullifyContext{}
+	nullifyContext.Update(func(cfg *LogConfig) {
+		cfg.Repository.Name = "logger"
+	})
+
+	snapshot := nullifyContext.Snapshot()
+	nullifyContext.Update(func(cfg *LogConfig) {
+		cfg.Repository.Name = "changed"
+	})
+
+	assert.Equal(t, "logger", snapshot.LogConfig.Repository.Name)
+	assert.Equal(t, "changed", nullifyContext.LogConfig.Repository.Name)
+}
+
+func TestSetSpanAttributesCopyOnWrite(t *testing.T) {
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", io.Discard)
+	require.NoError(t, err)
+
+	ctx = tracer.NewContext(ctx, sdktrace.NewTracerProvider(), "test-tracer")
+	ctx, parent := GetNullifyContext(ctx)
+	parentSpan := parent.CurrentSpan()
+
+	childCtx := L(ctx).SetSpanAttributes("child")
+	_, child := GetNullifyContext(childCtx)
+
+	assert.NotSame(t, parent, child)
+	assert.Equal(t, parentSpan, parent.CurrentSpan())
+	assert.Equal(t, trace.SpanFromContext(childCtx), child.CurrentSpan())
+}
+
+func TestNullifyContextConcurrentAccess(t *testing.T) {
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", io.Discard)
+	require.NoError(t, err)
+
+	ctx = tracer.NewContext(ctx, sdktrace.NewTracerProvider(), "test-tracer")
+	ctx, nullifyContext := GetNullifyContext(ctx)
+	ctx = WithServiceMetadata(ctx, Service{Name: "scanner"})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for j := range 50 {
+				nullifyContext.Update(func(cfg *LogConfig) {
+					cfg.Tool.Name = fmt.Sprintf("tool-%d", i)
+				})
+				childCtx := L(ctx).SetSpanAttributes(fmt.Sprintf("tool-call-%d-%d", i, j))
+				L(childCtx).Info("tool call", Int("call", j))
+				_ = LogConfigFromContext(childCtx)
+				_ = nullifyContext.Snapshot()
+			}
+		})
+	}
+	wg.Wait()
+
+	assert.Contains(t, nullifyContext.Snapshot().LogConfig.Tool.Name, "tool-")
+}
+
+func TestGetAWSConfigWithInstrumentation(t *testing.T) {
+	ctx := tracer.NewContext(t.Context(), sdktrace.NewTracerProvider(), "test-tracer")
+	ctx, nullifyContext := GetNullifyContext(ctx)
+	nullifyContext.AWSConfig = aws.Config{Region: "ap-southeast-2"}
+
+	cfg, err := GetAWSConfig(ctx, WithAWSInstrumentation())
+	require.NoError(t, err)
+	assert.Len(t, cfg.APIOptions, 1)
+	assert.Empty(t, nullifyContext.AWSConfig.APIOptions)
+
+	cfg, err = GetAWSConfig(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.APIOptions)
+}
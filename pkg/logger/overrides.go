@@ -0,0 +1,261 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// tenantOverridesEnvVar holds a JSON array of TenantOverride loaded at configure time
+	tenantOverridesEnvVar = "LOG_TENANT_OVERRIDES"
+
+	// overrideReasonKey is the field added to log entries and sampled spans of overridden tenants
+	overrideReasonKey = "log_override_reason"
+)
+
+// TenantOverride raises the log level and trace sampling of the contexts whose
+// LogConfig metadata matches exactly one of OrganizationID, InstallationID or RepositoryName.
+// When several overrides match, the repository override wins over the installation
+// override, which wins over the organization override.
+type TenantOverride struct {
+	OrganizationID string `json:"organizationId,omitempty"`
+	InstallationID string `json:"installationId,omitempty"`
+	RepositoryName string `json:"repositoryName,omitempty"`
+
+	// Level is the effective minimum log level, e.g. debug. It only lowers the configured level.
+	Level string `json:"level,omitempty"`
+	// TraceSampleRatio is the ratio of traces sampled when the configured sampler drops them, from 0 to 1
+	TraceSampleRatio float64 `json:"traceSampleRatio,omitempty"`
+	// Reason is recorded as log_override_reason on affected log entries and spans
+	Reason string `json:"reason"`
+}
+
+// tenantOverride is a validated TenantOverride
+type tenantOverride struct {
+	TenantOverride
+
+	level   zapcore.Level
+	sampler sdktrace.Sampler
+}
+
+// tenantOverrideTable indexes the overrides by the LogConfig field they match
+type tenantOverrideTable struct {
+	overrides      []TenantOverride
+	byRepository   map[string]*tenantOverride
+	byInstallation map[string]*tenantOverride
+	byOrganization map[string]*tenantOverride
+}
+
+var tenantOverrides atomic.Pointer[tenantOverrideTable]
+
+// SetTenantOverrides replaces the tenant override table, it is safe to call at runtime.
+// A nil or empty slice removes all overrides. The table is left unchanged if any override is invalid.
+func SetTenantOverrides(overrides []TenantOverride) error {
+	if len(overrides) == 0 {
+		tenantOverrides.Store(nil)
+		return nil
+	}
+
+	table := &tenantOverrideTable{
+		overrides:      slices.Clone(overrides),
+		byRepository:   map[string]*tenantOverride{},
+		byInstallation: map[string]*tenantOverride{},
+		byOrganization: map[string]*tenantOverride{},
+	}
+
+	for i, o := range overrides {
+		compiled, err := compileTenantOverride(o)
+		if err != nil {
+			return fmt.Errorf("tenant override %d: %w", i, err)
+		}
+
+		switch {
+		case o.RepositoryName != "":
+			table.byRepository[o.RepositoryName] = compiled
+		case o.InstallationID != "":
+			table.byInstallation[o.InstallationID] = compiled
+		default:
+			table.byOrganization[o.OrganizationID] = compiled
+		}
+	}
+
+	tenantOverrides.Store(table)
+	return nil
+}
+
+// LoadTenantOverrides replaces the tenant override table with the JSON array read from r
+func LoadTenantOverrides(r io.Reader) error {
+	var overrides []TenantOverride
+	if err := json.NewDecoder(r).Decode(&overrides); err != nil {
+		return fmt.Errorf("failed to decode tenant overrides: %w", err)
+	}
+	return SetTenantOverrides(overrides)
+}
+
+// TenantOverrides returns a copy of the current tenant override table
+func TenantOverrides() []TenantOverride {
+	if table := tenantOverrides.Load(); table != nil {
+		return slices.Clone(table.overrides)
+	}
+	return nil
+}
+
+func compileTenantOverride(o TenantOverride) (*tenantOverride, error) {
+	keys := 0
+	for _, key := range []string{o.OrganizationID, o.InstallationID, o.RepositoryName} {
+		if key != "" {
+			keys++
+		}
+	}
+	if keys != 1 {
+		return nil, errors.New("exactly one of organizationId, installationId or repositoryName must be set")
+	}
+
+	if o.Reason == "" {
+		return nil, errors.New("reason must be set")
+	}
+
+	compiled := &tenantOverride{TenantOverride: o, level: zapcore.InvalidLevel}
+	if o.Level != "" {
+		level, err := zapcore.ParseLevel(o.Level)
+		if err != nil {
+			return nil, err
+		}
+		compiled.level = level
+	}
+
+	if o.TraceSampleRatio < 0 || o.TraceSampleRatio > 1 {
+		return nil, fmt.Errorf("traceSampleRatio %v is not between 0 and 1", o.TraceSampleRatio)
+	}
+	if o.TraceSampleRatio > 0 {
+		compiled.sampler = sdktrace.TraceIDRatioBased(o.TraceSampleRatio)
+	}
+
+	return compiled, nil
+}
+
+// matchTenantOverride returns the override matching the LogConfig metadata of ctx, if any
+func matchTenantOverride(ctx context.Context) (*tenantOverride, bool) {
+	table := tenantOverrides.Load()
+	if table == nil || ctx == nil {
+		return nil, false
+	}
+
+	cfg := LogConfigFromContext(ctx)
+	if o, ok := table.byRepository[cfg.Repository.Name]; ok && cfg.Repository.Name != "" {
+		return o, true
+	}
+	if o, ok := table.byInstallation[cfg.Repository.InstallationID]; ok && cfg.Repository.InstallationID != "" {
+		return o, true
+	}
+	if o, ok := table.byOrganization[cfg.Repository.OrganizationID]; ok && cfg.Repository.OrganizationID != "" {
+		return o, true
+	}
+	return nil, false
+}
+
+// loadTenantOverridesFromEnv loads the tenant override table from LOG_TENANT_OVERRIDES, if set
+func loadTenantOverridesFromEnv() {
+	raw := os.Getenv(tenantOverridesEnvVar)
+	if raw == "" {
+		return
+	}
+
+	if err := LoadTenantOverrides(strings.NewReader(raw)); err != nil {
+		zap.L().Error("failed to load tenant overrides, continuing without", zap.Error(err))
+	}
+}
+
+// apply returns zl with the override level, if it is lower than the level of zl
+func (o *tenantOverride) apply(zl *zap.Logger) *zap.Logger {
+	if o.level == zapcore.InvalidLevel || o.level >= zl.Level() {
+		return zl
+	}
+
+	return zl.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if c, ok := core.(*levelCore); ok {
+			return &levelCore{Core: c.Core, level: o.level}
+		}
+		return core
+	}))
+}
+
+// levelCore filters entries by level in front of a core enabled for every level,
+// so that loggers of overridden tenants can swap in a lower level
+type levelCore struct {
+	zapcore.Core
+
+	level zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+// Level reports the minimum enabled level for zap.Logger.Level
+func (c *levelCore) Level() zapcore.Level {
+	return zapcore.LevelOf(c.level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce
+}
+
+// TenantOverrideSampler wraps base so that root spans, and children of sampled spans, dropped by base
+// are sampled at the TraceSampleRatio of the tenant override matching the context of the new span.
+// ConfigureProductionLogger and ConfigureDevelopmentLogger install it on the tracer provider.
+func TenantOverrideSampler(base sdktrace.Sampler) sdktrace.Sampler {
+	return &tenantOverrideSampler{base: base}
+}
+
+type tenantOverrideSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s *tenantOverrideSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.base.ShouldSample(p)
+	if result.Decision == sdktrace.RecordAndSample {
+		return result
+	}
+
+	// children of dropped spans stay dropped, so no sampled span has a parent missing from the trace
+	if parent := trace.SpanContextFromContext(p.ParentContext); parent.IsValid() && !parent.IsSampled() {
+		return result
+	}
+
+	o, ok := matchTenantOverride(p.ParentContext)
+	if !ok || o.sampler == nil {
+		return result
+	}
+
+	overridden := o.sampler.ShouldSample(p)
+	if overridden.Decision != sdktrace.RecordAndSample {
+		return result
+	}
+	overridden.Attributes = append(overridden.Attributes, attribute.String(overrideReasonKey, o.Reason))
+	return overridden
+}
+
+func (s *tenantOverrideSampler) Description() string {
+	return fmt.Sprintf("TenantOverrideSampler{%s}", s.base.Description())
+}
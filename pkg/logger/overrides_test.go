@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setTestTenantOverrides(t *testing.T, overrides ...TenantOverride) {
+	require.NoError(t, SetTenantOverrides(overrides))
+	t.Cleanup(func() { _ = SetTenantOverrides(nil) })
+}
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var entries []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestTenantOverrideLevel(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	setTestTenantOverrides(t,
+		TenantOverride{OrganizationID: "org-debug", Level: "debug", Reason: "INC-42 debugging"},
+		TenantOverride{RepositoryName: "quiet", Level: "error", Reason: "cannot raise level"},
+	)
+
+	L(ctx).Debug("dropped without override")
+	L(WithRepositoryMetadata(ctx, Repository{OrganizationID: "org-other"})).Debug("dropped for other tenant")
+	L(WithRepositoryMetadata(ctx, Repository{OrganizationID: "org-debug"})).Debug("kept for tenant")
+	L(WithRepositoryMetadata(ctx, Repository{Name: "quiet"})).Info("kept above override level")
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "kept for tenant", entries[0]["msg"])
+	assert.Equal(t, "debug", entries[0]["level"])
+	assert.Equal(t, "INC-42 debugging", entries[0]["log_override_reason"])
+
+	assert.Equal(t, "kept above override level", entries[1]["msg"])
+	assert.Equal(t, "cannot raise level", entries[1]["log_override_reason"])
+}
+
+func TestTenantOverridePrecedence(t *testing.T) {
+	setTestTenantOverrides(t,
+		TenantOverride{OrganizationID: "org-1", Reason: "organization"},
+		TenantOverride{InstallationID: "42", Reason: "installation"},
+		TenantOverride{RepositoryName: "logger", Reason: "repository"},
+	)
+
+	tests := []struct {
+		repository Repository
+		reason     string
+	}{
+		{Repository{OrganizationID: "org-1"}, "organization"},
+		{Repository{OrganizationID: "org-1", InstallationID: "42"}, "installation"},
+		{Repository{OrganizationID: "org-1", InstallationID: "42", Name: "logger"}, "repository"},
+		{Repository{OrganizationID: "org-2", Name: "other"}, ""},
+	}
+
+	for _, tt := range tests {
+		override, ok := matchTenantOverride(WithRepositoryMetadata(t.Context(), tt.repository))
+		if tt.reason == "" {
+			assert.False(t, ok)
+			continue
+		}
+		require.True(t, ok)
+		assert.Equal(t, tt.reason, override.Reason)
+	}
+}
+
+func TestSetTenantOverridesValidation(t *testing.T) {
+	setTestTenantOverrides(t, TenantOverride{OrganizationID: "org-1", Reason: "valid"})
+
+	invalid := []TenantOverride{
+		{Reason: "no key"},
+		{OrganizationID: "org-1", InstallationID: "42", Reason: "two keys"},
+		{OrganizationID: "org-1"},
+		{OrganizationID: "org-1", Level: "verbose", Reason: "bad level"},
+		{OrganizationID: "org-1", TraceSampleRatio: 1.5, Reason: "bad ratio"},
+	}
+	for _, override := range invalid {
+		assert.Error(t, SetTenantOverrides([]TenantOverride{override}), override.Reason)
+	}
+
+	assert.Equal(t, []TenantOverride{{OrganizationID: "org-1", Reason: "valid"}}, TenantOverrides())
+}
+
+func TestLoadTenantOverridesFromEnv(t *testing.T) {
+	t.Cleanup(func() { _ = SetTenantOverrides(nil) })
+	t.Setenv("LOG_TENANT_OVERRIDES", `[{"installationId":"42","level":"debug","traceSampleRatio":1,"reason":"support ticket"}]`)
+
+	_, err := ConfigureProductionLogger(t.Context(), "info", &bytes.Buffer{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []TenantOverride{{InstallationID: "42", Level: "debug", TraceSampleRatio: 1, Reason: "support ticket"}}, TenantOverrides())
+}
+
+func TestTenantOverrideSamplerWrapsConfiguredSampler(t *testing.T) {
+	t.Setenv("OTEL_TRACES_SAMPLER", "traceidratio")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0")
+
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+	setTestTenantOverrides(t, TenantOverride{InstallationID: "42", TraceSampleRatio: 1, Reason: "support ticket"})
+
+	_, span := tracer.StartNewSpan(ctx, "dropped")
+	span.End()
+	assert.False(t, span.SpanContext().IsSampled())
+
+	_, span = tracer.StartNewSpan(WithRepositoryMetadata(ctx, Repository{InstallationID: "42"}), "sampled")
+	span.End()
+	assert.True(t, span.SpanContext().IsSampled())
+}
+
+func TestTenantOverrideSampler(t *testing.T) {
+	setTestTenantOverrides(t, TenantOverride{InstallationID: "42", TraceSampleRatio: 1, Reason: "support ticket"})
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(TenantOverrideSampler(sdktrace.NeverSample())),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+	tr := tp.Tracer("test")
+
+	_, span := tr.Start(t.Context(), "dropped")
+	span.End()
+	_, span = tr.Start(WithRepositoryMetadata(t.Context(), Repository{InstallationID: "42"}), "sampled")
+	span.End()
+
+	// the child of a dropped span is not sampled, as its parent would be missing from the trace
+	parentCtx, parent := tr.Start(t.Context(), "dropped parent")
+	_, span = tr.Start(WithRepositoryMetadata(parentCtx, Repository{InstallationID: "42"}), "dropped child")
+	span.End()
+	parent.End()
+	assert.False(t, span.SpanContext().IsSampled())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "sampled", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("log_override_reason", "support ticket"))
+}
+
+func TestSamplerFromEnv(t *testing.T) {
+	unsampledParent := trace.ContextWithSpanContext(t.Context(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{1},
+		Remote:  true,
+	}))
+	params := sdktrace.SamplingParameters{ParentContext: unsampledParent, TraceID: trace.TraceID{1}, Name: "child"}
+
+	// the default samples every span, including children of unsampled remote parents
+	t.Setenv("OTEL_TRACES_SAMPLER", "")
+	assert.Equal(t, sdktrace.RecordAndSample, samplerFromEnv().ShouldSample(params).Decision)
+
+	t.Setenv("OTEL_TRACES_SAMPLER", "parentbased_always_on")
+	assert.Equal(t, sdktrace.Drop, samplerFromEnv().ShouldSample(params).Decision)
+
+	t.Setenv("OTEL_TRACES_SAMPLER", "unknown")
+	assert.Equal(t, sdktrace.RecordAndSample, samplerFromEnv().ShouldSample(params).Decision)
+}
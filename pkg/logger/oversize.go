@@ -0,0 +1,321 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// offloadErrorKey is the field added to log entries whose values failed to be offloaded
+	offloadErrorKey = "log_offload_error"
+
+	// DefaultBlobUploadTimeout is the default time a log write waits for the values of an entry
+	// to be offloaded, see WithBlobUploadTimeout
+	DefaultBlobUploadTimeout = 2 * time.Second
+
+	// DefaultMaxBlobUploads is the default number of uploads an OffloadOversized policy runs at once,
+	// see WithMaxBlobUploads
+	DefaultMaxBlobUploads = 4
+
+	// blobPreviewSize is the size of the start of an offloaded message kept in the log entry
+	blobPreviewSize = 256
+
+	// encodingGzipBase64 marks fields compressed by CompressOversized in <key>_encoding
+	encodingGzipBase64 = "gzip+base64"
+
+	// oversizePolicyEnvVar selects the OversizePolicy of outputs without WithOversizePolicy, chunk or compress
+	oversizePolicyEnvVar = "LOG_OVERSIZE_POLICY"
+)
+
+// OversizePolicy decides how an output handles log entries larger than its maximum entry size,
+// see WithOversizePolicy. Entries still too large after the policy is applied are chunked.
+type OversizePolicy interface {
+	// shrink returns the message and fields of an oversized entry with its largest values
+	// replaced, and whether any value was replaced
+	shrink(ctx context.Context, msg string, fields []zapcore.Field, encodedSize, maxEntrySize int, naming FieldNaming) (string, []zapcore.Field, bool)
+}
+
+// outputWriter is an output with its own maximum entry size or oversize policy
+type outputWriter struct {
+	io.Writer
+
+	maxEntrySize *int
+	policy       OversizePolicy
+}
+
+// newOutputWriter returns a copy of w if it is already an outputWriter, so options can be combined
+func newOutputWriter(w io.Writer) *outputWriter {
+	if output, ok := w.(*outputWriter); ok {
+		clone := *output
+		return &clone
+	}
+	return &outputWriter{Writer: w}
+}
+
+// WithOversizePolicy sets how log entries written to w that are larger than its maximum
+// entry size are handled, to be passed to ConfigureProductionLogger or ConfigureDevelopmentLogger.
+// It can be combined with WithMaxEntrySize. The default is ChunkOversized, or the policy
+// named by LOG_OVERSIZE_POLICY (chunk or compress).
+func WithOversizePolicy(w io.Writer, policy OversizePolicy) io.Writer {
+	output := newOutputWriter(w)
+	output.policy = policy
+	return output
+}
+
+// contextField carries the context attached to a logger to the cores, without being encoded
+func contextField(ctx context.Context) zapcore.Field {
+	return zapcore.Field{Type: zapcore.SkipType, Interface: ctx}
+}
+
+// contextFromFields returns the context added by contextField, or the background context
+func contextFromFields(fields []zapcore.Field) context.Context {
+	for _, f := range fields {
+		if ctx, ok := f.Interface.(context.Context); ok && f.Type == zapcore.SkipType {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// chunkPolicy splits oversized entries across several log entries
+type chunkPolicy struct{}
+
+// ChunkOversized splits oversized entries across several log entries, see WithMaxEntrySize
+func ChunkOversized() OversizePolicy {
+	return chunkPolicy{}
+}
+
+func (chunkPolicy) shrink(_ context.Context, msg string, fields []zapcore.Field, _, _ int, _ FieldNaming) (string, []zapcore.Field, bool) {
+	return msg, fields, false
+}
+
+// parseOversizePolicy returns the policy named by LOG_OVERSIZE_POLICY, chunk or compress
+func parseOversizePolicy(name string) (OversizePolicy, error) {
+	switch name {
+	case "", "chunk":
+		return ChunkOversized(), nil
+	case "compress":
+		return CompressOversized(), nil
+	default:
+		return ChunkOversized(), fmt.Errorf("unknown oversize policy %q", name)
+	}
+}
+
+// compressPolicy compresses the largest string fields of oversized entries
+type compressPolicy struct{}
+
+// CompressOversized replaces the largest string and byte string fields of oversized entries
+// with their gzip-compressed, base64-encoded value, marked with <key>_encoding: gzip+base64.
+// Values that do not get smaller are left as they are. Entries still too large are chunked.
+// Use chunking.Decode or the logdecode command to restore the original values.
+func CompressOversized() OversizePolicy {
+	return compressPolicy{}
+}
+
+func (compressPolicy) shrink(_ context.Context, msg string, fields []zapcore.Field, encodedSize, maxEntrySize int, naming FieldNaming) (string, []zapcore.Field, bool) {
+	values, _ := selectOversized(msg, fields, encodedSize, maxEntrySize)
+
+	var shrunk []zapcore.Field
+	for _, v := range values {
+		if v.field < 0 || v.encoding != "" {
+			continue
+		}
+
+		compressed, err := gzipBase64(v.value)
+		if err != nil || len(compressed) >= v.encodedSize {
+			continue
+		}
+
+		if shrunk == nil {
+			shrunk = make([]zapcore.Field, len(fields), len(fields)+len(values))
+			copy(shrunk, fields)
+		}
+		shrunk[v.field] = zap.String(v.key, compressed)
+		shrunk = append(shrunk, zap.String(naming.suffixKey(v.key, "_encoding"), encodingGzipBase64))
+	}
+
+	if shrunk == nil {
+		return msg, fields, false
+	}
+	return msg, shrunk, true
+}
+
+// gzipBase64 returns s gzip-compressed and base64-encoded
+func gzipBase64(s string) (string, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(w, s); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// errBlobUploadsBusy is logged in log_offload_error when all the uploads of a policy are in flight
+var errBlobUploadsBusy = errors.New("too many log blob uploads in flight")
+
+// offloadPolicy writes the largest values of oversized entries to a BlobStore
+type offloadPolicy struct {
+	store      BlobStore
+	timeout    time.Duration
+	maxUploads int
+
+	// uploads holds a token per upload in flight, including uploads a log write stopped waiting for
+	uploads chan struct{}
+}
+
+// OffloadOption configures an OffloadOversized policy
+type OffloadOption func(p *offloadPolicy)
+
+// WithBlobUploadTimeout sets how long a log write waits for the values of an oversized entry to be
+// offloaded before chunking them instead. The default is DefaultBlobUploadTimeout.
+func WithBlobUploadTimeout(timeout time.Duration) OffloadOption {
+	return func(p *offloadPolicy) {
+		p.timeout = timeout
+	}
+}
+
+// WithMaxBlobUploads limits the number of uploads in flight, including uploads that timed out but
+// have not returned. Values of entries logged while the limit is reached are chunked without waiting.
+// The default is DefaultMaxBlobUploads.
+func WithMaxBlobUploads(maxUploads int) OffloadOption {
+	return func(p *offloadPolicy) {
+		p.maxUploads = maxUploads
+	}
+}
+
+// OffloadOversized writes the largest values of oversized entries to store and replaces each
+// with a <key>_blob field holding the URI, size and SHA-256 of the object. An offloaded message
+// keeps its first bytes followed by a truncation marker. Each object is recorded as an event
+// on the span of the logger. Values that fail to upload are chunked, and the error is logged
+// in log_offload_error.
+//
+// Uploads run in the background, but the log write waits for them, up to WithBlobUploadTimeout,
+// as the entry holds their URIs. Values that time out, or that find WithMaxBlobUploads uploads
+// already in flight, are chunked, so a slow store delays writes by at most the timeout.
+func OffloadOversized(store BlobStore, opts ...OffloadOption) OversizePolicy {
+	p := &offloadPolicy{store: store, timeout: DefaultBlobUploadTimeout, maxUploads: DefaultMaxBlobUploads}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.uploads = make(chan struct{}, max(p.maxUploads, 1))
+	return p
+}
+
+// blobPut is the result of an upload
+type blobPut struct {
+	uri string
+	err error
+}
+
+// put uploads data in the background and returns its URI, or an error if it fails,
+// ctx is done first, or the maximum number of uploads is already in flight
+func (p *offloadPolicy) put(ctx context.Context, key string, data []byte) (string, error) {
+	select {
+	case p.uploads <- struct{}{}:
+	default:
+		return "", errBlobUploadsBusy
+	}
+
+	result := make(chan blobPut, 1)
+	go func() {
+		defer func() { <-p.uploads }()
+		uri, err := p.store.Put(ctx, key, data)
+		result <- blobPut{uri: uri, err: err}
+	}()
+
+	select {
+	case put := <-result:
+		return put.uri, put.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("log blob upload of %s: %w", key, ctx.Err())
+	}
+}
+
+// blobReference is the field logged in place of an offloaded value
+type blobReference struct {
+	URI    string
+	Size   int
+	SHA256 string
+}
+
+func (r blobReference) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("uri", r.URI)
+	enc.AddInt("size", r.Size)
+	enc.AddString("sha256", r.SHA256)
+	return nil
+}
+
+func (p *offloadPolicy) shrink(ctx context.Context, msg string, fields []zapcore.Field, encodedSize, maxEntrySize int, naming FieldNaming) (string, []zapcore.Field, bool) {
+	values, _ := selectOversized(msg, fields, encodedSize, maxEntrySize)
+
+	uploadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.timeout)
+	defer cancel()
+
+	offloaded := map[int]bool{}
+	var references []zapcore.Field
+	var errs []error
+	for _, v := range values {
+		data := []byte(v.value)
+		sum := sha256.Sum256(data)
+		digest := hex.EncodeToString(sum[:])
+
+		extension := ".txt"
+		if v.encoding == chunkEncodingJSON {
+			extension = ".json"
+		}
+
+		uri, err := p.put(uploadCtx, digest+extension, data)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		reference := blobReference{URI: uri, Size: len(data), SHA256: digest}
+		trace.SpanFromContext(ctx).AddEvent("log.blob.offloaded", trace.WithAttributes(
+			attribute.String("nullify.log.blob.field", v.key),
+			attribute.String("nullify.log.blob.uri", reference.URI),
+			attribute.Int("nullify.log.blob.size", reference.Size),
+			attribute.String("nullify.log.blob.sha256", reference.SHA256),
+		))
+
+		offloaded[v.field] = true
+		references = append(references, zap.Object(naming.suffixKey(v.key, "_blob"), reference))
+	}
+
+	if len(offloaded) == 0 && len(errs) == 0 {
+		return msg, fields, false
+	}
+
+	if offloaded[-1] {
+		msg = chunkString(msg, blobPreviewSize, false)[0] + truncationMarker
+	}
+
+	shrunk := make([]zapcore.Field, 0, len(fields)+len(references)+1)
+	for i, f := range fields {
+		if !offloaded[i] {
+			shrunk = append(shrunk, f)
+		}
+	}
+	shrunk = append(shrunk, references...)
+	if len(errs) > 0 {
+		shrunk = append(shrunk, zap.String(naming.Key(offloadErrorKey), errors.Join(errs...).Error()))
+	}
+
+	return msg, shrunk, true
+}
@@ -0,0 +1,232 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	mathrand "math/rand/v2"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nullify-platform/logger/pkg/logger/chunking"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+// failingBlobStore is a BlobStore whose uploads always fail
+type failingBlobStore struct{}
+
+func (failingBlobStore) Put(context.Context, string, []byte) (string, error) {
+	return "", errors.New("bucket unavailable")
+}
+
+func TestOffloadOversized(t *testing.T) {
+	store, err := NewFileBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	output := WithOversizePolicy(WithMaxEntrySize(&buf, 2000), OffloadOversized(store))
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", output)
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(ctx, "work")
+
+	report := strings.Repeat("r", 5000)
+	L(ctx).Info("scan finished", zap.String("report", report), zap.String("status", "ok"))
+	span.End()
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "ok", entries[0]["status"])
+	assert.NotContains(t, entries[0], "report")
+	assert.NotContains(t, entries[0], offloadErrorKey)
+
+	sum := sha256.Sum256([]byte(report))
+	reference := entries[0]["report_blob"].(map[string]any)
+	assert.Equal(t, hex.EncodeToString(sum[:]), reference["sha256"])
+	assert.Equal(t, float64(len(report)), reference["size"])
+
+	uri, err := url.Parse(reference["uri"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, "file", uri.Scheme)
+	data, err := os.ReadFile(uri.Path)
+	require.NoError(t, err)
+	assert.Equal(t, report, string(data))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, "log.blob.offloaded", spans[0].Events()[0].Name)
+	assert.Contains(t, spans[0].Events()[0].Attributes, attribute.String("nullify.log.blob.uri", reference["uri"].(string)))
+}
+
+func TestOffloadOversizedMessage(t *testing.T) {
+	store, err := NewFileBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", WithMaxEntrySize(WithOversizePolicy(&buf, OffloadOversized(store)), 2000))
+	require.NoError(t, err)
+
+	msg := strings.Repeat("m", 5000)
+	L(ctx).Info(msg)
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, msg[:blobPreviewSize]+truncationMarker, entries[0]["msg"])
+	assert.Contains(t, entries[0], "msg_blob")
+}
+
+func TestOffloadOversizedFallsBackToChunking(t *testing.T) {
+	var buf bytes.Buffer
+	output := WithOversizePolicy(WithMaxEntrySize(&buf, 2000), OffloadOversized(failingBlobStore{}))
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", output)
+	require.NoError(t, err)
+
+	report := strings.Repeat("r", 3000)
+	L(ctx).Info("scan finished", zap.String("report", report))
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 2)
+
+	var reassembled strings.Builder
+	for _, entry := range entries {
+		assert.Equal(t, "bucket unavailable", entry[offloadErrorKey])
+		assert.NotContains(t, entry, "report_blob")
+		reassembled.WriteString(entry["report"].(string))
+	}
+	assert.Equal(t, report, reassembled.String())
+}
+
+// stalledBlobStore is a BlobStore whose uploads block until release is closed, ignoring their context
+type stalledBlobStore struct {
+	release chan struct{}
+}
+
+func (s stalledBlobStore) Put(context.Context, string, []byte) (string, error) {
+	<-s.release
+	return "", errors.New("released")
+}
+
+func TestOffloadOversizedBoundsUploads(t *testing.T) {
+	store := stalledBlobStore{release: make(chan struct{})}
+	defer close(store.release)
+
+	var buf bytes.Buffer
+	policy := OffloadOversized(store, WithBlobUploadTimeout(10*time.Millisecond), WithMaxBlobUploads(1))
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", WithOversizePolicy(WithMaxEntrySize(&buf, 2000), policy))
+	require.NoError(t, err)
+
+	report := strings.Repeat("r", 3000)
+
+	// the write stops waiting for the stalled upload after the timeout and chunks the value
+	L(ctx).Info("scan finished", zap.String("report", report))
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 2)
+	assert.Contains(t, entries[0][offloadErrorKey], context.DeadlineExceeded.Error())
+
+	// the stalled upload still holds the only slot, so the next write chunks without waiting
+	buf.Reset()
+	start := time.Now()
+	L(ctx).Info("scan finished", zap.String("report", report))
+	assert.Less(t, time.Since(start), time.Second)
+
+	entries = decodeLogLines(t, &buf)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Equal(t, errBlobUploadsBusy.Error(), entry[offloadErrorKey])
+		assert.NotContains(t, entry, "report_blob")
+	}
+}
+
+func TestOffloadOversizedFieldNaming(t *testing.T) {
+	t.Setenv(fieldNamingEnvVar, string(FieldNamingOTel))
+
+	var buf bytes.Buffer
+	output := WithOversizePolicy(WithMaxEntrySize(&buf, 2000), OffloadOversized(failingBlobStore{}))
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", output)
+	require.NoError(t, err)
+	L(ctx).Info("scan finished", zap.String("report", strings.Repeat("r", 3000)))
+
+	store, err := NewFileBlobStore(t.TempDir())
+	require.NoError(t, err)
+	output = WithOversizePolicy(WithMaxEntrySize(&buf, 2000), OffloadOversized(store))
+	ctx, err = ConfigureProductionLogger(t.Context(), "info", output)
+	require.NoError(t, err)
+	L(ctx).Info("scan finished", zap.String("report", strings.Repeat("r", 3000)))
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 3)
+	assert.Contains(t, entries[0], "nullify.log.offload_error")
+	assert.Contains(t, entries[2], "report.blob")
+}
+
+func TestCompressOversized(t *testing.T) {
+	var buf bytes.Buffer
+	output := WithOversizePolicy(WithMaxEntrySize(&buf, 2000), CompressOversized())
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", output)
+	require.NoError(t, err)
+
+	report := strings.Repeat("finding: sql injection\n", 400)
+	L(ctx).Info("scan finished", zap.String("report", report), zap.ByteString("stack", []byte("short")))
+
+	entries := decodeLogLines(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, encodingGzipBase64, entries[0]["report_encoding"])
+	assert.NotContains(t, entries[0], "stack_encoding")
+
+	require.NoError(t, chunking.Decode(entries[0]))
+	assert.Equal(t, report, entries[0]["report"])
+	assert.NotContains(t, entries[0], "report_encoding")
+}
+
+func TestCompressOversizedFallsBackToChunking(t *testing.T) {
+	t.Setenv(oversizePolicyEnvVar, "compress")
+
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", WithMaxEntrySize(&buf, 2000))
+	require.NoError(t, err)
+
+	// seeded, as gzip stores some random inputs uncompressed, which would not be compressed
+	random := make([]byte, 3000)
+	_, _ = mathrand.NewChaCha8([32]byte{}).Read(random)
+	incompressible := hex.EncodeToString(random)
+	L(ctx).Info("scan finished", zap.String("report", incompressible))
+
+	lines := buf.String()
+	entries := slices.Collect(chunking.Reassemble(strings.NewReader(lines)))
+	require.Len(t, entries, 1)
+	assert.Equal(t, []string{"report"}, entries[0].ChunkedKeys)
+	assert.Equal(t, incompressible, entries[0].Fields["report"])
+	assert.Contains(t, lines, `"report_encoding":"gzip+base64"`)
+}
+
+func TestCompressOversizedSkipsIncompressible(t *testing.T) {
+	var buf bytes.Buffer
+	output := WithOversizePolicy(WithMaxEntrySize(&buf, 2000), CompressOversized())
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", output)
+	require.NoError(t, err)
+
+	random := make([]byte, 2500)
+	_, _ = rand.Read(random)
+	L(ctx).Info("scan finished", zap.String("payload", base64.StdEncoding.EncodeToString(random)))
+
+	entries := decodeLogLines(t, &buf)
+	require.Greater(t, len(entries), 1)
+	for _, entry := range entries {
+		assert.NotContains(t, entry, "payload_encoding")
+	}
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"github.com/nullify-platform/logger/pkg/logger/internal/libraryfield"
+	"github.com/nullify-platform/logger/pkg/logger/meter"
+	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Scan phase names with a matching ErrorType
+const (
+	ScanPhasePreScan  = "prescan"
+	ScanPhaseScan     = "scan"
+	ScanPhasePostScan = "postscan"
+)
+
+const (
+	scanOutcomeSuccess = "success"
+	scanOutcomeFailure = "failure"
+)
+
+var scanPhaseErrorTypes = map[string]ErrorType{
+	ScanPhasePreScan:  ErrorTypePreScan,
+	ScanPhaseScan:     ErrorTypeScan,
+	ScanPhasePostScan: ErrorTypePostScan,
+}
+
+// ScanTracker tracks the lifecycle of a single scan: a root span for the scan,
+// a child span per phase, consistent start/finish/failed log entries and
+// duration and outcome metrics recorded using the meter from the context
+type ScanTracker struct {
+	ScanID     string
+	Repository Repository
+
+	ctx   context.Context
+	span  trace.Span
+	start time.Time
+}
+
+// ScanPhase tracks a single phase of a scan, created by ScanTracker.Phase
+type ScanPhase struct {
+	Name string
+
+	ctx   context.Context
+	span  trace.Span
+	start time.Time
+}
+
+// StartScan starts a new root span for the scan and logs that the scan has started.
+// The returned context carries the scan span and a logger annotated with the scan ID
+// and repository, and should be passed to ScanTracker.Phase.
+func StartScan(ctx context.Context, scanID string, repository Repository) (context.Context, *ScanTracker) {
+	attrs := append([]attribute.KeyValue{attribute.String("scan.id", scanID)}, logConfigAttributes(LogConfig{Repository: repository})...)
+
+	ctx = WithRepositoryMetadata(ctx, repository)
+	ctx = L(ctx).NewChild(libraryfield.Mark(String("scan_id", scanID))).InjectIntoContext(ctx)
+	ctx, span := tracer.StartNewRootSpan(ctx, "scan", trace.WithAttributes(attrs...))
+
+	s := &ScanTracker{
+		ScanID:     scanID,
+		Repository: repository,
+		ctx:        ctx,
+		span:       span,
+		start:      time.Now(),
+	}
+
+	L(ctx).Info("scan started")
+	return ctx, s
+}
+
+// Phase starts a child span for the named phase, e.g. ScanPhasePreScan,
+// and logs that the phase has started
+func (s *ScanTracker) Phase(ctx context.Context, name string) (context.Context, *ScanPhase) {
+	ctx = L(ctx).NewChild(libraryfield.Mark(String("scan_phase", name))).InjectIntoContext(ctx)
+	ctx, span := tracer.StartNewSpan(ctx, "scan."+name, trace.WithAttributes(
+		attribute.String("scan.id", s.ScanID),
+		attribute.String("scan.phase", name),
+	))
+
+	p := &ScanPhase{
+		Name:  name,
+		ctx:   ctx,
+		span:  span,
+		start: time.Now(),
+	}
+
+	L(ctx).Info("scan phase started")
+	return ctx, p
+}
+
+// End finishes the phase, logging whether it finished or failed and recording
+// its duration and outcome. err may be nil.
+func (p *ScanPhase) End(err error) {
+	duration := time.Since(p.start)
+	defer p.span.End()
+
+	outcome := endScanSpan(p.ctx, p.span, "scan phase", duration, p.errorType(err), err)
+
+	recordScanMetrics(p.ctx, "scan.phase", duration, outcome, attribute.String("phase", p.Name))
+}
+
+func (p *ScanPhase) errorType(err error) ErrorType {
+	if errType, ok := scanPhaseErrorTypes[p.Name]; ok {
+		return errType
+	}
+	return ClassifyError(err)
+}
+
+// End finishes the scan, logging whether it finished or failed and recording
+// its duration and outcome. err may be nil.
+func (s *ScanTracker) End(err error) {
+	duration := time.Since(s.start)
+	defer s.span.End()
+
+	outcome := endScanSpan(s.ctx, s.span, "scan", duration, ErrorTypeScan, err)
+
+	recordScanMetrics(s.ctx, "scan", duration, outcome)
+}
+
+// endScanSpan logs the finished or failed entry, sets the span status and returns the outcome
+func endScanSpan(ctx context.Context, span trace.Span, name string, duration time.Duration, errType ErrorType, err error) string {
+	durationField := libraryfield.Mark(Int64("duration_ms", duration.Milliseconds()))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		// the error is recorded on the span above, and logged once in error_message
+		fields := append([]Field{durationField}, WithErrorInfo(ErrorFields{Type: errType, Message: err.Error()})...)
+		logErrorWithoutSpan(L(ctx), name+" failed", fields...)
+		return scanOutcomeFailure
+	}
+
+	span.SetStatus(codes.Ok, "")
+	L(ctx).Info(name+" finished", durationField)
+	return scanOutcomeSuccess
+}
+
+// recordScanMetrics records <prefix>.duration and <prefix>.outcomes using the meter from the context
+func recordScanMetrics(ctx context.Context, prefix string, duration time.Duration, outcome string, attrs ...attribute.KeyValue) {
+	m := meter.FromContext(ctx)
+	if m == nil {
+		return
+	}
+
+	attrs = append(attrs, attribute.String("outcome", outcome))
+
+	histogram, err := m.Float64Histogram(
+		prefix+".duration",
+		metric.WithDescription("Duration of "+prefix),
+		metric.WithUnit("s"),
+	)
+	if err == nil {
+		histogram.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
+	}
+
+	counter, err := m.Int64Counter(
+		prefix+".outcomes",
+		metric.WithDescription("Number of completed "+prefix+" by outcome"),
+	)
+	if err == nil {
+		counter.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nullify-platform/logger/pkg/logger/meter"
+	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestScanTracker(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	ctx = tracer.NewContext(ctx, tp, "test-tracer")
+	ctx = meter.NewContext(ctx, mp, "test-meter")
+
+	ctx, scan := StartScan(ctx, "scan-1", Repository{Name: "logger", OrganizationID: "org-1"})
+
+	preCtx, phase := scan.Phase(ctx, ScanPhasePreScan)
+	L(preCtx).Info("cloning")
+	phase.End(nil)
+
+	_, phase = scan.Phase(ctx, ScanPhaseScan)
+	phase.End(errors.New("semgrep exited 2"))
+
+	scan.End(errors.New("scan phase failed"))
+
+	var entries []map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+
+	msgs := make([]string, len(entries))
+	for i, entry := range entries {
+		msgs[i] = entry["msg"].(string)
+		assert.Equal(t, "scan-1", entry["scan_id"])
+		assert.Equal(t, "logger", entry["repositoryName"])
+		assert.Equal(t, "org-1", entry["organizationId"])
+	}
+	assert.Equal(t, []string{
+		"scan started",
+		"scan phase started",
+		"cloning",
+		"scan phase finished",
+		"scan phase started",
+		"scan phase failed",
+		"scan failed",
+	}, msgs)
+	assert.Equal(t, "prescan", entries[2]["scan_phase"])
+	assert.Equal(t, "scan_error", entries[5]["error_type"])
+	assert.Equal(t, "semgrep exited 2", entries[5]["error_message"])
+	assert.NotContains(t, entries[5], "error")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	root := spans[2]
+	assert.Equal(t, "scan", root.Name())
+	assert.Equal(t, codes.Error, root.Status().Code)
+	assert.Equal(t, "scan.prescan", spans[0].Name())
+	assert.Equal(t, codes.Ok, spans[0].Status().Code)
+	assert.Equal(t, root.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+
+	// the error is recorded once, and the log message does not replace the status description
+	for _, span := range []sdktrace.ReadOnlySpan{spans[1], root} {
+		require.Len(t, span.Events(), 1)
+		assert.Equal(t, "exception", span.Events()[0].Name)
+	}
+	assert.Equal(t, "semgrep exited 2", spans[1].Status().Description)
+	assert.Equal(t, "scan phase failed", root.Status().Description)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	outcomes := map[string]int64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name != "scan.phase.outcomes" {
+			continue
+		}
+		for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+			phase, _ := dp.Attributes.Value("phase")
+			outcome, _ := dp.Attributes.Value("outcome")
+			outcomes[phase.AsString()+"/"+outcome.AsString()] = dp.Value
+		}
+	}
+	assert.Equal(t, map[string]int64{"prescan/success": 1, "scan/failure": 1}, outcomes)
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// ErrSectionKeyCollision is returned by RegisterContextSection when a json key of the
+// section is already used by LogConfig, by another registered section, or by a field the logger writes
+var ErrSectionKeyCollision = errors.New("context section key collision")
+
+// reservedSectionKeys are the keys of the entry and of the fields added by the logger, which sections
+// cannot use as they would overwrite them
+var reservedSectionKeys = []string{
+	"timestamp", "level", "logger", "caller", "msg", "stacktrace",
+	"service.version", "service.name", "trace-id", "span-id",
+	"scan_id", "scan_phase", "duration_ms",
+	"error_type", "error_message", "error_traceback",
+	"agent", "repository", "service", "tool_call", "llm", "finding",
+	"requestSummary", "requestBody", "responseBody", "statusCode", "requestHeaders", "responseHeaders",
+	overrideReasonKey, offloadErrorKey,
+}
+
+// reservedSectionKey returns the reserved key that key is written as in any field naming, if any
+func reservedSectionKey(key string) (string, bool) {
+	for _, naming := range []FieldNaming{FieldNamingCompat, FieldNamingSnake, FieldNamingCamel, FieldNamingOTel} {
+		for _, reserved := range reservedSectionKeys {
+			if naming.Key(key) == naming.Key(reserved) {
+				return reserved, true
+			}
+		}
+	}
+	return "", false
+}
+
+// metadataSection is a registered custom section type
+type metadataSection struct {
+	typ  reflect.Type
+	plan metadataPlan
+}
+
+// sectionValue is the value of a custom section attached to a context
+type sectionValue struct {
+	section *metadataSection
+	value   any
+}
+
+var (
+	sectionsMu sync.Mutex
+	// sectionKeys maps every metadata json key to the name of the section that owns it
+	sectionKeys = func() map[string]string {
+		keys := map[string]string{}
+		for _, f := range logConfigPlan {
+			keys[f.key] = "LogConfig"
+		}
+		return keys
+	}()
+	registeredSections = map[reflect.Type]*metadataSection{}
+)
+
+// ContextSection is a custom metadata section registered with RegisterContextSection.
+// The json-tagged string fields of its values are added to log entries and span attributes
+// alongside the LogConfig metadata.
+type ContextSection[T any] struct {
+	section *metadataSection
+}
+
+// RegisterContextSection registers T, a struct with json-tagged string fields, as a context section.
+// Nested structs are flattened as in LogConfig. It fails if T is not a struct, is already
+// registered, or shares a json key with LogConfig, another section, or a field the logger writes
+// such as msg, level or trace-id, in any field naming.
+func RegisterContextSection[T any]() (*ContextSection[T], error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("context section %s is not a struct", t)
+	}
+
+	sectionsMu.Lock()
+	defer sectionsMu.Unlock()
+
+	if _, ok := registeredSections[t]; ok {
+		return nil, fmt.Errorf("context section %s is already registered", t)
+	}
+
+	plan := compileMetadataPlan(t, nil, nil)
+	seen := map[string]bool{}
+	for _, f := range plan {
+		if reserved, ok := reservedSectionKey(f.key); ok {
+			return nil, fmt.Errorf("%w: %s.%s is written by the logger as %s", ErrSectionKeyCollision, t, f.key, reserved)
+		}
+		if owner, ok := sectionKeys[f.key]; ok {
+			return nil, fmt.Errorf("%w: %s.%s is already used by %s", ErrSectionKeyCollision, t, f.key, owner)
+		}
+		if seen[f.key] {
+			return nil, fmt.Errorf("%w: %s.%s is used twice", ErrSectionKeyCollision, t, f.key)
+		}
+		seen[f.key] = true
+	}
+
+	for key := range seen {
+		sectionKeys[key] = t.String()
+	}
+	section := &metadataSection{typ: t, plan: plan}
+	registeredSections[t] = section
+
+	return &ContextSection[T]{section: section}, nil
+}
+
+// MustRegisterContextSection is like RegisterContextSection but panics on error,
+// for package level variables
+func MustRegisterContextSection[T any]() *ContextSection[T] {
+	section, err := RegisterContextSection[T]()
+	if err != nil {
+		panic(err)
+	}
+	return section
+}
+
+// With returns a copy of ctx with the non-empty fields of value merged into the section
+// attached to ctx, following the same rules as WithLogConfig
+func (s *ContextSection[T]) With(ctx context.Context, value T) context.Context {
+	metadata, _ := ctx.Value(logConfigCtxKey{}).(*contextMetadata)
+
+	merged := reflect.New(s.section.typ).Elem()
+	var sections []sectionValue
+	index := -1
+	if metadata != nil {
+		sections = slices.Clone(metadata.sections)
+		index = slices.IndexFunc(sections, func(v sectionValue) bool { return v.section == s.section })
+	}
+	if index >= 0 {
+		merged.Set(reflect.ValueOf(sections[index].value))
+	}
+	s.section.plan.merge(merged, reflect.ValueOf(value))
+
+	if index >= 0 {
+		sections[index].value = merged.Interface()
+	} else {
+		sections = append(sections, sectionValue{section: s.section, value: merged.Interface()})
+	}
+
+	var config LogConfig
+	if metadata != nil {
+		config = metadata.config
+	}
+	updated := (&contextMetadata{sections: sections}).withConfig(config)
+	return context.WithValue(ctx, logConfigCtxKey{}, updated)
+}
+
+// From returns the section value attached to ctx, or the zero value
+func (s *ContextSection[T]) From(ctx context.Context) T {
+	var zero T
+	if ctx == nil {
+		return zero
+	}
+
+	if metadata, ok := ctx.Value(logConfigCtxKey{}).(*contextMetadata); ok {
+		for _, v := range metadata.sections {
+			if v.section == s.section {
+				return v.value.(T)
+			}
+		}
+	}
+	return zero
+}
@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/nullify-platform/logger/pkg/logger/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type testCustomer struct {
+	ID   string `json:"customerId"`
+	Tier string `json:"customerTier"`
+}
+
+type testJob struct {
+	ID    string `json:"jobId"`
+	Queue string `json:"jobQueue"`
+	Retry struct {
+		Attempt string `json:"jobAttempt"`
+	}
+}
+
+// testBatch has tag options and an unexported nested struct, which sections ignore
+type testBatch struct {
+	ID       string `json:"batchId,omitempty"`
+	internal struct {
+		Owner string `json:"batchOwner"`
+	}
+}
+
+var (
+	testCustomerSection = MustRegisterContextSection[testCustomer]()
+	testJobSection      = MustRegisterContextSection[testJob]()
+	testBatchSection    = MustRegisterContextSection[testBatch]()
+)
+
+func TestContextSectionMerges(t *testing.T) {
+	ctx := testCustomerSection.With(t.Context(), testCustomer{ID: "cus-1", Tier: "free"})
+	child := testCustomerSection.With(ctx, testCustomer{Tier: "enterprise"})
+	child = WithRepositoryMetadata(child, Repository{Name: "logger"})
+
+	assert.Equal(t, testCustomer{ID: "cus-1", Tier: "free"}, testCustomerSection.From(ctx))
+	assert.Equal(t, testCustomer{ID: "cus-1", Tier: "enterprise"}, testCustomerSection.From(child))
+	assert.Equal(t, "logger", LogConfigFromContext(child).Repository.Name)
+	assert.Equal(t, testJob{}, testJobSection.From(child))
+}
+
+func TestContextSectionFieldsAndAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	ctx = tracer.NewContext(ctx, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), "test-tracer")
+	ctx, _ = GetNullifyContext(ctx)
+
+	job := testJob{ID: "job-1", Queue: "scans"}
+	job.Retry.Attempt = "2"
+	ctx = testJobSection.With(ctx, job)
+	ctx = testCustomerSection.With(ctx, testCustomer{ID: "cus-1"})
+	ctx = WithToolMetadata(ctx, Tool{Name: "semgrep"})
+
+	L(ctx).Info("hello")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "job-1", entry["jobId"])
+	assert.Equal(t, "scans", entry["jobQueue"])
+	assert.Equal(t, "2", entry["jobAttempt"])
+	assert.Equal(t, "cus-1", entry["customerId"])
+	assert.Equal(t, "semgrep", entry["toolName"])
+	assert.NotContains(t, entry, "customerTier")
+
+	spanCtx := L(ctx).SetSpanAttributes("work")
+	span := trace.SpanFromContext(spanCtx)
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Subset(t, spans[0].Attributes(), []attribute.KeyValue{
+		attribute.String("jobId", "job-1"),
+		attribute.String("customerId", "cus-1"),
+		attribute.String("toolName", "semgrep"),
+	})
+}
+
+func TestContextSectionTagOptionsAndUnexportedFields(t *testing.T) {
+	var buf bytes.Buffer
+	ctx, err := ConfigureProductionLogger(t.Context(), "info", &buf)
+	require.NoError(t, err)
+
+	batch := testBatch{ID: "batch-1"}
+	batch.internal.Owner = "hidden"
+	ctx = testBatchSection.With(ctx, batch)
+	ctx = testBatchSection.With(ctx, batch)
+	assert.Equal(t, testBatch{ID: "batch-1"}, testBatchSection.From(ctx))
+
+	L(ctx).Info("hello")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "batch-1", entry["batchId"])
+	assert.NotContains(t, entry, "batchId,omitempty")
+	assert.NotContains(t, entry, "batchOwner")
+}
+
+func TestRegisterContextSectionErrors(t *testing.T) {
+	type collidesWithLogConfig struct {
+		Name string `json:"repositoryName"`
+	}
+	type collidesWithSection struct {
+		Customer string `json:"customerId"`
+	}
+	type duplicateKeys struct {
+		A string `json:"dup"`
+		B struct {
+			C string `json:"dup"`
+		}
+	}
+
+	_, err := RegisterContextSection[collidesWithLogConfig]()
+	assert.ErrorIs(t, err, ErrSectionKeyCollision)
+
+	_, err = RegisterContextSection[collidesWithSection]()
+	assert.ErrorIs(t, err, ErrSectionKeyCollision)
+
+	_, err = RegisterContextSection[duplicateKeys]()
+	assert.ErrorIs(t, err, ErrSectionKeyCollision)
+
+	type collidesWithMessage struct {
+		Message string `json:"msg"`
+	}
+	type collidesWithLevel struct {
+		Level string `json:"level"`
+	}
+	type collidesWithTraceID struct {
+		TraceID string `json:"trace-id"`
+	}
+	type collidesWithScanID struct {
+		ScanID string `json:"scanId"`
+	}
+	_, err = RegisterContextSection[collidesWithMessage]()
+	assert.ErrorIs(t, err, ErrSectionKeyCollision)
+	_, err = RegisterContextSection[collidesWithLevel]()
+	assert.ErrorIs(t, err, ErrSectionKeyCollision)
+	_, err = RegisterContextSection[collidesWithTraceID]()
+	assert.ErrorIs(t, err, ErrSectionKeyCollision)
+	// scanId is written as scan_id in the snake naming, where it would overwrite scan_id
+	_, err = RegisterContextSection[collidesWithScanID]()
+	assert.ErrorIs(t, err, ErrSectionKeyCollision)
+
+	_, err = RegisterContextSection[testCustomer]()
+	assert.Error(t, err)
+
+	_, err = RegisterContextSection[string]()
+	assert.Error(t, err)
+
+	assert.Panics(t, func() { MustRegisterContextSection[collidesWithLogConfig]() })
+}
@@ -0,0 +1,86 @@
+package tracer
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const awsMiddlewareID = "NullifyOTelSpan"
+
+// InstrumentAWSConfig returns a copy of cfg with middleware that starts a client span
+// for every AWS API call made by clients created from it. Spans record the service,
+// operation, region, request ID and error of the call, and SQS SendMessage and SNS Publish
+// inputs are sent with the trace context injected into a copy of their message attributes.
+// Calls made with a context without a tracer are not instrumented.
+func InstrumentAWSConfig(cfg aws.Config) aws.Config {
+	// clone so the middleware is not appended to the slice of the original config
+	cfg.APIOptions = append(slices.Clone(cfg.APIOptions), addAWSMiddleware)
+	return cfg
+}
+
+func addAWSMiddleware(stack *middleware.Stack) error {
+	// added last so the service metadata is registered when the span starts
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc(awsMiddlewareID, awsSpanMiddleware), middleware.After)
+}
+
+func awsSpanMiddleware(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	t := FromContext(ctx)
+	if t == nil {
+		return next.HandleInitialize(ctx, in)
+	}
+
+	service := awsmiddleware.GetServiceID(ctx)
+	operation := awsmiddleware.GetOperationName(ctx)
+
+	ctx, span := t.Start(ctx, service+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "aws-api"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", operation),
+			attribute.String("cloud.region", awsmiddleware.GetRegion(ctx)),
+		),
+	)
+	defer span.End()
+
+	// the trace context is injected into copies, so the caller's input can be reused or shared
+	switch input := in.Parameters.(type) {
+	case *sqs.SendMessageInput:
+		clone := *input
+		clone.MessageAttributes = maps.Clone(input.MessageAttributes)
+		InjectTracingIntoSQSMessage(ctx, &clone)
+		in.Parameters = &clone
+	case *sns.PublishInput:
+		clone := *input
+		clone.MessageAttributes = maps.Clone(input.MessageAttributes)
+		InjectTracingIntoSNS(ctx, &clone)
+		in.Parameters = &clone
+	}
+
+	out, metadata, err := next.HandleInitialize(ctx, in)
+
+	if requestID, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+		span.SetAttributes(attribute.String("aws.request_id", requestID))
+	}
+	if response, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok {
+		span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return out, metadata, err
+}
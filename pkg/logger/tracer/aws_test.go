@@ -0,0 +1,168 @@
+package tracer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqsTypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newSQSStandIn returns a local SQS endpoint that records the message attributes it receives
+func newSQSStandIn(t *testing.T, status int, body string, attributes *map[string]any) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			MessageAttributes map[string]any
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&input))
+		*attributes = input.MessageAttributes
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Header().Set("x-amzn-RequestId", "req-123")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newInstrumentedSQSClient(endpoint string) *sqs.Client {
+	cfg := InstrumentAWSConfig(aws.Config{
+		Region: "ap-southeast-2",
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+		}),
+		RetryMaxAttempts: 1,
+	})
+	return sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+	})
+}
+
+func TestInstrumentAWSConfig(t *testing.T) {
+	original := otel.GetTextMapPropagator()
+	t.Cleanup(func() { otel.SetTextMapPropagator(original) })
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	recorder := tracetest.NewSpanRecorder()
+	ctx := NewContext(t.Context(), sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), "test-aws")
+
+	var attributes map[string]any
+	server := newSQSStandIn(t, http.StatusOK, `{"MessageId":"msg-1"}`, &attributes)
+
+	output, err := newInstrumentedSQSClient(server.URL).SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(server.URL + "/queue"),
+		MessageBody: aws.String("hello"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "msg-1", aws.ToString(output.MessageId))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	assert.Equal(t, "SQS.SendMessage", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Subset(t, span.Attributes(), []attribute.KeyValue{
+		attribute.String("rpc.system", "aws-api"),
+		attribute.String("rpc.service", "SQS"),
+		attribute.String("rpc.method", "SendMessage"),
+		attribute.String("cloud.region", "ap-southeast-2"),
+		attribute.String("aws.request_id", "req-123"),
+		attribute.Int("http.response.status_code", http.StatusOK),
+	})
+
+	require.Contains(t, attributes, "traceparent")
+	traceparent := attributes["traceparent"].(map[string]any)["StringValue"]
+	assert.Contains(t, traceparent, span.SpanContext().SpanID().String())
+}
+
+func TestInstrumentAWSConfigDoesNotMutateInput(t *testing.T) {
+	original := otel.GetTextMapPropagator()
+	t.Cleanup(func() { otel.SetTextMapPropagator(original) })
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ctx := NewContext(t.Context(), sdktrace.NewTracerProvider(), "test-aws")
+
+	var attributes map[string]any
+	server := newSQSStandIn(t, http.StatusOK, `{"MessageId":"msg-1"}`, &attributes)
+
+	input := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(server.URL + "/queue"),
+		MessageBody: aws.String("hello"),
+		MessageAttributes: map[string]sqsTypes.MessageAttributeValue{
+			"tenant": {DataType: aws.String("String"), StringValue: aws.String("acme")},
+		},
+	}
+	_, err := newInstrumentedSQSClient(server.URL).SendMessage(ctx, input)
+	require.NoError(t, err)
+
+	assert.Contains(t, attributes, "traceparent")
+	assert.Contains(t, attributes, "tenant")
+	assert.Len(t, input.MessageAttributes, 1)
+	assert.NotContains(t, input.MessageAttributes, "traceparent")
+
+	// inputs without attributes are left without them
+	input = &sqs.SendMessageInput{QueueUrl: aws.String(server.URL + "/queue"), MessageBody: aws.String("hello")}
+	_, err = newInstrumentedSQSClient(server.URL).SendMessage(ctx, input)
+	require.NoError(t, err)
+	assert.Contains(t, attributes, "traceparent")
+	assert.Nil(t, input.MessageAttributes)
+}
+
+func TestInstrumentAWSConfigRecordsErrors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	ctx := NewContext(t.Context(), sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), "test-aws")
+
+	var attributes map[string]any
+	server := newSQSStandIn(t, http.StatusBadRequest, `{"__type":"com.amazonaws.sqs#QueueDoesNotExist","message":"no queue"}`, &attributes)
+
+	_, err := newInstrumentedSQSClient(server.URL).SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(server.URL + "/missing"),
+		MessageBody: aws.String("hello"),
+	})
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("http.response.status_code", http.StatusBadRequest))
+	require.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, "exception", spans[0].Events()[0].Name)
+}
+
+func TestInstrumentAWSConfigWithoutTracer(t *testing.T) {
+	var attributes map[string]any
+	server := newSQSStandIn(t, http.StatusOK, `{"MessageId":"msg-1"}`, &attributes)
+
+	_, err := newInstrumentedSQSClient(server.URL).SendMessage(t.Context(), &sqs.SendMessageInput{
+		QueueUrl:    aws.String(server.URL + "/queue"),
+		MessageBody: aws.String("hello"),
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, attributes, "traceparent")
+}
+
+func TestInstrumentAWSConfigDoesNotMutateOriginal(t *testing.T) {
+	cfg := aws.Config{}
+	cfg.APIOptions = make([]func(*middleware.Stack) error, 0, 4)
+
+	instrumented := InstrumentAWSConfig(cfg)
+	assert.Len(t, instrumented.APIOptions, 1)
+	assert.Empty(t, cfg.APIOptions)
+	assert.Nil(t, cfg.APIOptions[:1][0])
+}